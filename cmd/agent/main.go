@@ -1,13 +1,17 @@
 package main
 
 import (
+	"flag"
 	"log"
 
 	"github.com/Shemistan/agent/internal/app/agent"
 )
 
 func main() {
-	if err := agent.Run(); err != nil {
+	configPath := flag.String("config", "", "path to app.toml (defaults to $CONFIG_PATH or ./app.toml)")
+	flag.Parse()
+
+	if err := agent.Run(*configPath); err != nil {
 		log.Fatalf("Agent failed: %v", err)
 	}
 }
@@ -1,19 +1,43 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
-	"os"
 
 	"github.com/Shemistan/agent/internal/app/migrator"
 )
 
 func main() {
-	migrationDir := "migration"
-	if len(os.Args) > 1 {
-		migrationDir = os.Args[1]
+	configPath := flag.String("config", "", "path to app.toml (defaults to $CONFIG_PATH or ./app.toml)")
+	migrationDir := flag.String("dir", "migration", "directory with migration files")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), `Usage: migrator [flags] [dir] [command]
+
+Commands:
+  up [N]          apply all (or the next N) pending migrations (default)
+  down [N]        roll back the last N applied migrations (default 1)
+  goto VERSION    migrate up or down to VERSION (0 rolls back everything)
+  status          show applied and pending migrations
+  redo            roll back the last migration and apply it again
+
+A leading dir argument overrides -dir, e.g. "migrator migration" applies all pending migrations.
+
+Flags:
+`)
+		flag.PrintDefaults()
 	}
+	flag.Parse()
+
+	dir, command, args := migrator.ParseArgs(*migrationDir, flag.Args())
 
-	if err := migrator.Run(migrationDir); err != nil {
+	err := migrator.Run(migrator.Options{
+		ConfigPath:   *configPath,
+		MigrationDir: dir,
+		Command:      command,
+		Args:         args,
+	})
+	if err != nil {
 		log.Fatalf("Migrator failed: %v", err)
 	}
 }
@@ -3,6 +3,7 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 	"time"
@@ -13,54 +14,223 @@ import (
 // Handler contains all HTTP handlers for the agent service
 type Handler struct {
 	healthService       service.HealthService
+	probeService        service.ProbeService
 	managerCheckService service.ManagerCheckService
+	historyService      service.ManagerCheckHistoryService
+	slaService          service.SLAService
+	scheduler           service.Scheduler
 	logger              *slog.Logger
 }
 
 // NewHandler creates a new Handler instance
 func NewHandler(
 	healthService service.HealthService,
+	probeService service.ProbeService,
 	managerCheckService service.ManagerCheckService,
+	historyService service.ManagerCheckHistoryService,
+	slaService service.SLAService,
+	scheduler service.Scheduler,
 	logger *slog.Logger,
 ) *Handler {
 	return &Handler{
 		healthService:       healthService,
+		probeService:        probeService,
 		managerCheckService: managerCheckService,
+		historyService:      historyService,
+		slaService:          slaService,
+		scheduler:           scheduler,
 		logger:              logger,
 	}
 }
 
 // HealthResponse represents the response for the /health endpoint
 type HealthResponse struct {
-	Status string `json:"status"`
+	Status service.Status `json:"status"`
+}
+
+// ProbeCheckResponse represents the outcome of a single liveness or readiness check.
+// Duration and details are only reported in verbose mode.
+type ProbeCheckResponse struct {
+	Name           string         `json:"name"`
+	Status         service.Status `json:"status"`
+	Error          string         `json:"error,omitempty"`
+	DurationMillis *float64       `json:"duration_ms,omitempty"`
+	Details        map[string]any `json:"details,omitempty"`
+}
+
+// ProbeResponse represents the response for the /livez and /readyz endpoints
+type ProbeResponse struct {
+	Status service.Status       `json:"status"`
+	Checks []ProbeCheckResponse `json:"checks"`
+}
+
+// TimingResponse represents the latency breakdown of a check in milliseconds.
+// Phases that did not happen, e.g. on a reused connection, are omitted.
+type TimingResponse struct {
+	DNSMillis     *float64 `json:"dns_ms,omitempty"`
+	ConnectMillis *float64 `json:"connect_ms,omitempty"`
+	TLSMillis     *float64 `json:"tls_ms,omitempty"`
+	TTFBMillis    *float64 `json:"ttfb_ms,omitempty"`
+	TotalMillis   *float64 `json:"total_ms,omitempty"`
 }
 
 // ManagerCheckItemResponse represents a single manager check result
 type ManagerCheckItemResponse struct {
-	ManagerURL string `json:"manager_url"`
-	Status     string `json:"status"`
-	HTTPStatus *int   `json:"http_status,omitempty"`
-	Error      string `json:"error,omitempty"`
+	Name          string         `json:"name,omitempty"`
+	ManagerURL    string         `json:"manager_url"`
+	Status        service.Status `json:"status"`
+	HTTPStatus    *int           `json:"http_status,omitempty"`
+	Error         string         `json:"error,omitempty"`
+	TLSVersion    string         `json:"tls_version,omitempty"`
+	CertExpiresAt *time.Time     `json:"cert_expires_at,omitempty"`
+	Timing        TimingResponse `json:"timing"`
+	Attempts      int            `json:"attempts"`
+
+	FailedAssertions []AssertionFailureResponse `json:"failed_assertions,omitempty"`
+}
+
+// AssertionFailureResponse represents a response assertion that did not hold
+type AssertionFailureResponse struct {
+	Source   string  `json:"source"`
+	Path     string  `json:"path"`
+	Op       string  `json:"op"`
+	Expected string  `json:"expected,omitempty"`
+	Actual   *string `json:"actual"` // null when the value is missing
+	Severity string  `json:"severity"`
 }
 
 // ManagerCheckResponse represents the response for the /check-manager endpoint
 type ManagerCheckResponse struct {
-	Status   string                     `json:"status"`
+	Status   service.Status             `json:"status"` // derived by the configured status policy
 	Managers []ManagerCheckItemResponse `json:"managers"`
 }
 
-// Health handles GET /health requests
+// ErrorResponse represents a response for rejected requests
+type ErrorResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+}
+
+// ManagerCheckRecordResponse represents a single stored manager check
+type ManagerCheckRecordResponse struct {
+	ID           int64          `json:"id"`
+	CheckedAt    time.Time      `json:"checked_at"`
+	ManagerURL   string         `json:"manager_url"`
+	Status       service.Status `json:"status"`
+	HTTPStatus   *int           `json:"http_status"`
+	ErrorMessage *string        `json:"error_message"`
+	Timing       TimingResponse `json:"timing"`
+	Attempts     int            `json:"attempts"`
+}
+
+// ManagerChecksResponse represents the response for the /manager-checks endpoint
+type ManagerChecksResponse struct {
+	Checks     []ManagerCheckRecordResponse `json:"checks"`
+	NextCursor string                       `json:"next_cursor,omitempty"` // opaque, passed back as the cursor parameter
+}
+
+// SLAWindowResponse represents availability of a manager over one rolling window.
+// Ratios and means are null when there is no data to derive them from.
+type SLAWindowResponse struct {
+	Window               string    `json:"window"`
+	From                 time.Time `json:"from"`
+	To                   time.Time `json:"to"`
+	Partial              bool      `json:"partial"` // from is later than the start of the window
+	ObservedSeconds      float64   `json:"observed_seconds"`
+	UptimePercent        *float64  `json:"uptime_percent"` // degraded time counts as up
+	DegradedPercent      *float64  `json:"degraded_percent"`
+	Outages              int       `json:"outages"`
+	MTBFSeconds          *float64  `json:"mtbf_seconds"`
+	MTTRSeconds          *float64  `json:"mttr_seconds"`
+	LongestOutageSeconds float64   `json:"longest_outage_seconds"`
+}
+
+// ManagerSLAResponse represents the response for the /managers/{url}/sla endpoint
+type ManagerSLAResponse struct {
+	ManagerURL string              `json:"manager_url"`
+	Windows    []SLAWindowResponse `json:"windows"`
+}
+
+// SchedulerResponse represents the response for the /scheduler endpoint
+type SchedulerResponse struct {
+	Enabled         bool       `json:"enabled"`
+	Running         bool       `json:"running"`
+	IntervalSeconds float64    `json:"interval_seconds"`
+	LastRunAt       *time.Time `json:"last_run_at,omitempty"`
+	LastDurationMS  int64      `json:"last_duration_ms"`
+	NextRunAt       *time.Time `json:"next_run_at,omitempty"`
+	Runs            int64      `json:"runs"`
+	SkippedTicks    int64      `json:"skipped_ticks"`
+}
+
+// Health handles GET /health requests: it reports liveness and records the call in the background.
+// With ?verbose the individual liveness checks are included.
 func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
+	startedAt := time.Now()
+	defer func() {
+		h.healthService.RecordHealthCall(service.HealthCall{
+			CalledAt:  startedAt,
+			CallerIP:  remoteHost(r),
+			UserAgent: r.UserAgent(),
+			Latency:   time.Since(startedAt),
+		})
+	}()
+
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	if err := h.healthService.HandleHealth(ctx); err != nil {
-		h.logger.Error("health handler: failed to save health call", slog.String("error", err.Error()))
-		h.respondJSON(w, http.StatusInternalServerError, HealthResponse{Status: "error"})
+	report := h.probeService.Liveness(ctx)
+	if isVerbose(r) {
+		h.respondProbe(w, report, true)
 		return
 	}
+	h.respondJSON(w, probeStatusCode(report), HealthResponse{Status: report.Status})
+}
+
+// Livez handles GET /livez requests; a failure means the process should be restarted
+func (h *Handler) Livez(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	h.respondProbe(w, h.probeService.Liveness(ctx), isVerbose(r))
+}
+
+// Readyz handles GET /readyz requests; a failure means the agent should not receive traffic
+func (h *Handler) Readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	h.respondProbe(w, h.probeService.Readiness(ctx), isVerbose(r))
+}
+
+// respondProbe writes a probe report, with durations and details in verbose mode
+func (h *Handler) respondProbe(w http.ResponseWriter, report service.HealthReport, verbose bool) {
+	response := ProbeResponse{
+		Status: report.Status,
+		Checks: make([]ProbeCheckResponse, 0, len(report.Checks)),
+	}
+	for _, check := range report.Checks {
+		item := ProbeCheckResponse{
+			Name:   check.Name,
+			Status: check.Status,
+			Error:  check.Error,
+		}
+		if verbose {
+			item.DurationMillis = ptr(float64(check.Duration) / float64(time.Millisecond))
+			item.Details = check.Details
+		}
+		response.Checks = append(response.Checks, item)
+	}
 
-	h.respondJSON(w, http.StatusOK, HealthResponse{Status: "success"})
+	h.respondJSON(w, probeStatusCode(report), response)
+}
+
+// probeStatusCode returns 503 for failed probes so that orchestrators act on the status code alone
+func probeStatusCode(report service.HealthReport) int {
+	if report.Status == service.StatusError {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
 }
 
 // CheckManager handles GET /check-manager requests
@@ -72,7 +242,7 @@ func (h *Handler) CheckManager(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		h.logger.Error("check-manager handler: service error", slog.String("error", err.Error()))
 		h.respondJSON(w, http.StatusInternalServerError, ManagerCheckResponse{
-			Status:   "error",
+			Status:   service.StatusError,
 			Managers: []ManagerCheckItemResponse{},
 		})
 		return
@@ -80,34 +250,166 @@ func (h *Handler) CheckManager(w http.ResponseWriter, r *http.Request) {
 
 	// Build response with all manager check results
 	managers := make([]ManagerCheckItemResponse, 0, len(results.Results))
-	overallStatus := "success"
 
 	for _, result := range results.Results {
 		item := ManagerCheckItemResponse{
+			Name:       result.ManagerName,
 			ManagerURL: result.ManagerURL,
 			Status:     result.Status,
+			TLSVersion: result.TLSVersion,
+			Timing:     newTimingResponse(result.Timing),
+			Attempts:   result.Attempts,
+		}
+
+		for _, f := range result.FailedAssertions {
+			failure := AssertionFailureResponse{
+				Source:   f.Source,
+				Path:     f.Path,
+				Op:       f.Op,
+				Expected: f.Expected,
+				Severity: string(f.Severity),
+			}
+			if !f.Missing {
+				failure.Actual = ptr(f.Actual)
+			}
+			item.FailedAssertions = append(item.FailedAssertions, failure)
 		}
 
 		if result.HTTPStatus != 0 {
 			item.HTTPStatus = &result.HTTPStatus
 		}
 
-		if result.Status != "success" {
+		if !result.CertNotAfter.IsZero() {
+			item.CertExpiresAt = &result.CertNotAfter
+		}
+
+		if result.Status != service.StatusSuccess {
 			item.Error = result.ErrorMessage
-			overallStatus = "error"
 		}
 
 		managers = append(managers, item)
 	}
 
 	response := ManagerCheckResponse{
-		Status:   overallStatus,
+		Status:   results.Status,
 		Managers: managers,
 	}
 
 	h.respondJSON(w, http.StatusOK, response)
 }
 
+// ListManagerChecks handles GET /manager-checks requests.
+// Supported query parameters: manager_url, status, from, to (RFC 3339), cursor and limit.
+func (h *Handler) ListManagerChecks(w http.ResponseWriter, r *http.Request) {
+	query, err := parseManagerCheckQuery(r.URL.Query())
+	if err != nil {
+		h.respondJSON(w, http.StatusBadRequest, ErrorResponse{Status: "error", Error: err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	page, err := h.historyService.ListManagerChecks(ctx, query)
+	if err != nil {
+		h.logger.Error("manager-checks handler: service error", slog.String("error", err.Error()))
+		h.respondJSON(w, http.StatusInternalServerError, ErrorResponse{Status: "error", Error: "failed to list manager checks"})
+		return
+	}
+
+	response := ManagerChecksResponse{
+		Checks:     make([]ManagerCheckRecordResponse, 0, len(page.Checks)),
+		NextCursor: page.NextCursor.String(),
+	}
+	for _, check := range page.Checks {
+		response.Checks = append(response.Checks, ManagerCheckRecordResponse{
+			ID:           check.ID,
+			CheckedAt:    check.CheckedAt,
+			ManagerURL:   check.ManagerURL,
+			Status:       check.Status,
+			HTTPStatus:   check.HTTPStatus,
+			ErrorMessage: check.ErrorMessage,
+			Timing:       newTimingResponse(check.Timing),
+			Attempts:     check.Attempts,
+		})
+	}
+
+	h.respondJSON(w, http.StatusOK, response)
+}
+
+// ManagerSLA handles GET /managers/{url}/sla requests.
+// The manager URL must be path-escaped, e.g. /managers/https%3A%2F%2Fmanager2%3A8443/sla.
+func (h *Handler) ManagerSLA(w http.ResponseWriter, r *http.Request) {
+	managerURL := r.PathValue("url")
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	report, err := h.slaService.ManagerSLA(ctx, managerURL)
+	if errors.Is(err, service.ErrUnknownManager) {
+		h.respondJSON(w, http.StatusNotFound, ErrorResponse{Status: "error", Error: "unknown manager " + managerURL})
+		return
+	}
+	if err != nil {
+		h.logger.Error("sla handler: service error", slog.String("error", err.Error()))
+		h.respondJSON(w, http.StatusInternalServerError, ErrorResponse{Status: "error", Error: "failed to compute SLA"})
+		return
+	}
+
+	response := ManagerSLAResponse{
+		ManagerURL: report.ManagerURL,
+		Windows:    make([]SLAWindowResponse, 0, len(report.Windows)),
+	}
+	for _, sla := range report.Windows {
+		item := SLAWindowResponse{
+			Window:               sla.Window,
+			From:                 sla.From,
+			To:                   sla.To,
+			Partial:              sla.Partial,
+			ObservedSeconds:      sla.Observed.Seconds(),
+			Outages:              sla.Outages,
+			LongestOutageSeconds: sla.LongestOutage.Seconds(),
+		}
+		if sla.HasData {
+			item.UptimePercent = &sla.UptimePercent
+			item.DegradedPercent = &sla.DegradedPercent
+		}
+		if sla.MTBF > 0 {
+			item.MTBFSeconds = ptr(sla.MTBF.Seconds())
+		}
+		if sla.MTTR > 0 {
+			item.MTTRSeconds = ptr(sla.MTTR.Seconds())
+		}
+		response.Windows = append(response.Windows, item)
+	}
+
+	h.respondJSON(w, http.StatusOK, response)
+}
+
+// Scheduler handles GET /scheduler requests
+func (h *Handler) Scheduler(w http.ResponseWriter, _ *http.Request) {
+	status := h.scheduler.Status()
+
+	response := SchedulerResponse{
+		Enabled:         status.Enabled,
+		Running:         status.Running,
+		IntervalSeconds: status.Interval.Seconds(),
+		LastDurationMS:  status.LastDuration.Milliseconds(),
+		Runs:            status.Runs,
+		SkippedTicks:    status.SkippedTicks,
+	}
+
+	if !status.LastRunAt.IsZero() {
+		response.LastRunAt = &status.LastRunAt
+	}
+
+	if status.Enabled && !status.NextRunAt.IsZero() {
+		response.NextRunAt = &status.NextRunAt
+	}
+
+	h.respondJSON(w, http.StatusOK, response)
+}
+
 // respondJSON writes structured JSON responses and logs encoding errors.
 func (h *Handler) respondJSON(w http.ResponseWriter, statusCode int, payload interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -117,3 +419,25 @@ func (h *Handler) respondJSON(w http.ResponseWriter, statusCode int, payload int
 		h.logger.Error("failed to encode response", slog.String("error", err.Error()))
 	}
 }
+
+// newTimingResponse converts a timing breakdown to milliseconds, omitting phases that did not happen
+func newTimingResponse(timing service.CheckTiming) TimingResponse {
+	millis := func(d time.Duration) *float64 {
+		if d <= 0 {
+			return nil
+		}
+		return ptr(float64(d) / float64(time.Millisecond))
+	}
+
+	return TimingResponse{
+		DNSMillis:     millis(timing.DNS),
+		ConnectMillis: millis(timing.Connect),
+		TLSMillis:     millis(timing.TLSHandshake),
+		TTFBMillis:    millis(timing.FirstByte),
+		TotalMillis:   millis(timing.Total),
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
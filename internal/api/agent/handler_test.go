@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Shemistan/agent/internal/service"
+	svc "github.com/Shemistan/agent/internal/service/agent"
+)
+
+// MockHealthService implements service.HealthService interface
+type MockHealthService struct {
+	mu    sync.Mutex
+	calls []service.HealthCall
+}
+
+func (m *MockHealthService) RecordHealthCall(call service.HealthCall) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, call)
+}
+
+func newTestRouter(t *testing.T, databaseErr error) (*Router, *MockHealthService) {
+	t.Helper()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	registry := svc.NewHealthRegistry(50*time.Millisecond, logger)
+	registry.Register("scheduler", svc.LivenessCheck, func(ctx context.Context) (map[string]any, error) {
+		return nil, nil
+	})
+	registry.Register("database", svc.ReadinessCheck, func(ctx context.Context) (map[string]any, error) {
+		return nil, databaseErr
+	})
+
+	health := &MockHealthService{}
+	handler := NewHandler(health, registry, nil, nil, nil, nil, logger)
+	return NewRouter(handler, RouterConfig{}), health
+}
+
+func TestHandler_HealthStaysUpWhileDatabaseIsDown(t *testing.T) {
+	router, health := newTestRouter(t, errors.New("connection refused"))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected /health to return 200 while the database is down, got %d: %s", rec.Code, rec.Body)
+	}
+	if len(health.calls) != 1 {
+		t.Errorf("Expected the call to be recorded, got %d", len(health.calls))
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected /readyz to return 503 while the database is down, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,62 @@
+package agent
+
+import (
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/Shemistan/agent/internal/service"
+)
+
+// requireClientCert rejects requests that did not present a client certificate
+// verified against the configured CA
+func (h *Handler) requireClientCert(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			h.logger.Warn("rejected request without verified client certificate",
+				slog.String("path", r.URL.Path),
+				slog.String("remote_addr", r.RemoteAddr),
+			)
+			h.respondJSON(w, http.StatusForbidden, HealthResponse{Status: service.StatusError})
+			return
+		}
+
+		h.logger.Debug("client certificate verified",
+			slog.String("path", r.URL.Path),
+			slog.String("subject", r.TLS.VerifiedChains[0][0].Subject.String()),
+		)
+		next(w, r)
+	}
+}
+
+// RequestTracker counts in-flight and completed requests so shutdown can report what it drained
+type RequestTracker struct {
+	next      http.Handler
+	active    atomic.Int64
+	completed atomic.Int64
+}
+
+// NewRequestTracker wraps next with request accounting
+func NewRequestTracker(next http.Handler) *RequestTracker {
+	return &RequestTracker{next: next}
+}
+
+// ServeHTTP implements http.Handler interface
+func (t *RequestTracker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	t.active.Add(1)
+	defer func() {
+		t.active.Add(-1)
+		t.completed.Add(1)
+	}()
+	t.next.ServeHTTP(w, r)
+}
+
+// Active returns the number of requests currently being handled
+func (t *RequestTracker) Active() int64 {
+	return t.active.Load()
+}
+
+// Completed returns the number of requests handled so far
+func (t *RequestTracker) Completed() int64 {
+	return t.completed.Load()
+}
@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Shemistan/agent/internal/service"
+)
+
+// parseManagerCheckQuery converts /manager-checks query parameters into a service query
+func parseManagerCheckQuery(values url.Values) (service.ManagerCheckQuery, error) {
+	query := service.ManagerCheckQuery{
+		ManagerURL: values.Get("manager_url"),
+		Status:     service.Status(values.Get("status")),
+	}
+
+	if query.Status != "" && !query.Status.IsValid() {
+		return query, fmt.Errorf("invalid status %q: expected success, degraded or error", query.Status)
+	}
+
+	var err error
+	if query.From, err = parseTimeParam(values, "from"); err != nil {
+		return query, err
+	}
+	if query.To, err = parseTimeParam(values, "to"); err != nil {
+		return query, err
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		return query, fmt.Errorf("from must be before to")
+	}
+
+	if raw := values.Get("cursor"); raw != "" {
+		if query.Cursor, err = service.ParseManagerCheckCursor(raw); err != nil {
+			return query, err
+		}
+	}
+
+	if raw := values.Get("limit"); raw != "" {
+		query.Limit, err = strconv.Atoi(raw)
+		if err != nil || query.Limit <= 0 {
+			return query, fmt.Errorf("invalid limit %q", raw)
+		}
+	}
+
+	return query, nil
+}
+
+// parseTimeParam parses an optional RFC 3339 timestamp parameter
+func parseTimeParam(values url.Values, name string) (time.Time, error) {
+	raw := values.Get(name)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q: expected RFC 3339 timestamp", name, raw)
+	}
+	return parsed, nil
+}
+
+// isVerbose reports whether the verbose query parameter is set, e.g. ?verbose or ?verbose=true
+func isVerbose(r *http.Request) bool {
+	values := r.URL.Query()
+	if !values.Has("verbose") {
+		return false
+	}
+	verbose, err := strconv.ParseBool(values.Get("verbose"))
+	return err != nil || verbose
+}
+
+// remoteHost returns the host part of the remote address of the request.
+// Forwarding headers are ignored: the caller of interest is the load balancer itself.
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
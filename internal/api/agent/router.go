@@ -4,16 +4,53 @@ import (
 	"net/http"
 )
 
+// RouteInstrumenter wraps route handlers with per-route instrumentation
+type RouteInstrumenter interface {
+	InstrumentRoute(route string, next http.HandlerFunc) http.HandlerFunc
+}
+
+// RouterConfig represents optional router behaviour
+type RouterConfig struct {
+	// RequireClientCert serves manager-facing routes only to clients that
+	// presented a certificate signed by the configured CA
+	RequireClientCert bool
+	// Instrumenter, if set, wraps every route; the route label is its pattern
+	Instrumenter RouteInstrumenter
+	// Metrics, if set, is served on GET /metrics
+	Metrics http.Handler
+}
+
 // Router creates and configures the HTTP router
 type Router struct {
 	mux *http.ServeMux
 }
 
 // NewRouter creates a new Router instance
-func NewRouter(handler *Handler) *Router {
+func NewRouter(handler *Handler, cfg RouterConfig) *Router {
 	mux := http.NewServeMux()
-	mux.HandleFunc("GET /health", handler.Health)
-	mux.HandleFunc("GET /check-manager", handler.CheckManager)
+	handle := func(pattern string, next http.HandlerFunc) {
+		if cfg.Instrumenter != nil {
+			next = cfg.Instrumenter.InstrumentRoute(pattern, next)
+		}
+		mux.HandleFunc(pattern, next)
+	}
+	managerOnly := func(next http.HandlerFunc) http.HandlerFunc {
+		if cfg.RequireClientCert {
+			return handler.requireClientCert(next)
+		}
+		return next
+	}
+
+	handle("GET /health", handler.Health)
+	handle("GET /livez", handler.Livez)
+	handle("GET /readyz", handler.Readyz)
+	handle("GET /check-manager", managerOnly(handler.CheckManager))
+	handle("GET /manager-checks", handler.ListManagerChecks)
+	handle("GET /managers/{url}/sla", handler.ManagerSLA)
+	handle("GET /scheduler", handler.Scheduler)
+	if cfg.Metrics != nil {
+		handle("GET /metrics", cfg.Metrics.ServeHTTP)
+	}
 	return &Router{mux: mux}
 }
 
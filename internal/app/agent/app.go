@@ -2,24 +2,35 @@ package agent
 
 import (
 	"context"
+	"crypto/tls"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	api "github.com/Shemistan/agent/internal/api/agent"
+	"github.com/Shemistan/agent/internal/app/migrator"
 	"github.com/Shemistan/agent/internal/config"
+	"github.com/Shemistan/agent/internal/metrics"
+	"github.com/Shemistan/agent/internal/notifier"
+	"github.com/Shemistan/agent/internal/service"
 	svc "github.com/Shemistan/agent/internal/service/agent"
 	stg "github.com/Shemistan/agent/internal/storage/agent"
+	"github.com/Shemistan/agent/migration"
 	_ "github.com/lib/pq" // nolint:gci
 )
 
-// Run initializes and starts the agent service
-func Run() error {
+// Run initializes and starts the agent service and blocks until it is stopped
+// by SIGINT/SIGTERM or a server error
+func Run(configPath string) error {
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := config.Load(configPath)
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
@@ -27,58 +38,341 @@ func Run() error {
 	// Initialize logger
 	logger := initLogger(cfg.ServiceEnv)
 	logger.Info("Starting agent service", slog.String("service_name", cfg.ServiceName))
+	logConfigSources(cfg, logger)
+
+	// Stop on SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// Connect to PostgreSQL
 	db, err := connectDB(cfg, logger)
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
+	closeDB := sync.OnceValue(db.Close)
 	defer func() {
-		if cerr := db.Close(); cerr != nil {
+		if cerr := closeDB(); cerr != nil {
 			logger.Warn("failed to close database", slog.String("error", cerr.Error()))
 		}
 	}()
 	logger.Info("Connected to database")
 
-	// Create HTTP client for manager service
+	// Create HTTP client for manager service.
+	// Requests are bounded by per-target check timeouts rather than a client-wide one.
+	managerTimeout := time.Duration(cfg.GetManagerTimeout()) * time.Second
+	clientTLS, err := newClientTLSConfig(cfg.Manager.TLS)
+	if err != nil {
+		return fmt.Errorf("failed to configure manager TLS: %w", err)
+	}
+	// The CA, client certificate and server name of clientTLS are only used for targets with manager_tls,
+	// the HTTP prober derives their transport from this one
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{MinVersion: clientTLS.MinVersion}
 	httpClient := &http.Client{
-		Timeout: time.Duration(cfg.GetManagerTimeout()) * time.Second,
+		Transport: transport,
 	}
 
+	// Initialize metrics
+	agentMetrics := metrics.New(db)
+
 	// Initialize storage layer
 	storage := stg.NewStorage(db, logger)
 
 	// Initialize service layer
-	healthService := svc.NewHealthService(storage, logger)
+	healthService := svc.NewHealthService(storage, svc.HealthCallPolicy{
+		SampleEvery: cfg.HealthCalls.SampleEvery,
+		MinInterval: time.Duration(cfg.HealthCalls.MinIntervalMillis) * time.Millisecond,
+		BufferSize:  cfg.HealthCalls.BufferSize,
+	}, logger)
+	alertService := svc.NewAlertService(
+		storage,
+		storage,
+		newAlertSinks(cfg),
+		svc.AlertPolicy{
+			FailureThreshold:  cfg.Alerting.FailureThreshold,
+			RecoveryThreshold: cfg.Alerting.RecoveryThreshold,
+			RenotifyInterval:  time.Duration(cfg.Alerting.RenotifyIntervalSeconds) * time.Second,
+			DedupInterval:     time.Duration(cfg.Alerting.DedupIntervalSeconds) * time.Second,
+		},
+		logger,
+	)
+	// Manager checks are buffered and written in batches so that a slow database does not hold back checks.
+	// Alerting evaluates the stored history, so it is fed once the checks are written.
+	writerCfg := stg.BatchWriterConfig{
+		BufferSize:     cfg.Writer.BufferSize,
+		BatchSize:      cfg.Writer.BatchSize,
+		FlushInterval:  time.Duration(cfg.Writer.FlushIntervalMillis) * time.Millisecond,
+		EnqueueTimeout: time.Duration(cfg.Writer.EnqueueTimeoutMillis) * time.Millisecond,
+	}
+	if cfg.Alerting.Enabled {
+		writerCfg.OnWritten = alertService.ObserveWrittenChecks
+	}
+	// Checks that cannot be written are spooled to disk and replayed once the database is back
+	var outbox *stg.Outbox
+	if cfg.Writer.OutboxPath != "" {
+		outbox, err = stg.NewOutbox(storage, stg.OutboxConfig{
+			Path:           cfg.Writer.OutboxPath,
+			ReplayInterval: time.Duration(cfg.Writer.OutboxReplayIntervalSeconds) * time.Second,
+			BatchSize:      cfg.Writer.BatchSize,
+			// Replayed checks are evaluated like fresh ones; alerting orders them by checked_at
+			OnWritten: writerCfg.OnWritten,
+		}, logger)
+		if err != nil {
+			return fmt.Errorf("failed to create outbox: %w", err)
+		}
+		writerCfg.Spool = outbox
+	}
+	checkWriter := stg.NewBatchWriter(storage, writerCfg, logger)
+
+	checkOpts := []svc.ManagerCheckOption{
+		svc.WithConcurrency(cfg.Manager.Concurrency),
+		svc.WithCheckTimeout(managerTimeout),
+		svc.WithTLSConfig(clientTLS),
+		svc.WithRetry(svc.RetryPolicy{
+			MaxAttempts: cfg.Manager.Retry.MaxAttempts,
+			BaseDelay:   time.Duration(cfg.Manager.Retry.BaseDelayMillis) * time.Millisecond,
+			MaxDelay:    time.Duration(cfg.Manager.Retry.MaxDelayMillis) * time.Millisecond,
+		}),
+		svc.WithStatusPolicy(svc.StatusPolicy{
+			Mode:               cfg.Manager.StatusPolicy.Mode,
+			Quorum:             cfg.Manager.StatusPolicy.Quorum,
+			MinUpWeightPercent: cfg.Manager.StatusPolicy.MinUpWeightPercent,
+		}),
+		svc.WithObserver(agentMetrics),
+	}
 	managerCheckService := svc.NewManagerCheckService(
 		httpClient,
+		checkWriter,
+		managerTargets(cfg, logger),
+		logger,
+		checkOpts...,
+	)
+	historyService := svc.NewManagerCheckHistoryService(storage, logger)
+	slaService := svc.NewSLAService(storage, cfg.GetManagerURLs(), logger)
+	scheduler := svc.NewCheckScheduler(
+		managerCheckService,
+		time.Duration(cfg.Scheduler.IntervalSeconds)*time.Second,
+		time.Duration(cfg.Scheduler.JitterSeconds)*time.Second,
+		logger,
+	)
+
+	retention := svc.NewRetentionJob(
 		storage,
-		cfg.GetManagerURLs(),
+		svc.RetentionPolicy{
+			Interval:         time.Duration(cfg.Retention.IntervalSeconds) * time.Second,
+			BatchSize:        cfg.Retention.BatchSize,
+			HealthCallsTTL:   time.Duration(cfg.Retention.HealthCallsTTLDays) * 24 * time.Hour,
+			ManagerChecksTTL: time.Duration(cfg.Retention.ManagerChecksTTLDays) * 24 * time.Hour,
+			RollupLag:        time.Duration(cfg.Retention.RollupLagSeconds) * time.Second,
+		},
 		logger,
 	)
+	var partitionTTL time.Duration
+	if cfg.Retention.Enabled {
+		partitionTTL = time.Duration(cfg.Retention.ManagerChecksTTLDays) * 24 * time.Hour
+	}
+	partitions := svc.NewPartitionMaintainer(
+		storage,
+		storage,
+		svc.PartitionPolicy{
+			Interval:    time.Duration(cfg.Retention.IntervalSeconds) * time.Second,
+			MonthsAhead: cfg.Retention.PartitionMonthsAhead,
+			TTL:         partitionTTL,
+			DetachOnly:  cfg.Retention.DetachExpiredPartitions,
+		},
+		logger,
+	)
+
+	probes, err := newHealthRegistry(cfg, storage, scheduler, logger)
+	if err != nil {
+		return err
+	}
 
 	// Initialize HTTP layer
-	handler := api.NewHandler(healthService, managerCheckService, logger)
-	router := api.NewRouter(handler)
+	handler := api.NewHandler(
+		healthService,
+		probes,
+		managerCheckService,
+		historyService,
+		slaService,
+		scheduler,
+		logger,
+	)
+	requireClientCert := cfg.TLS.Enabled && cfg.TLS.CAFile != ""
+	router := api.NewRouter(handler, api.RouterConfig{
+		RequireClientCert: requireClientCert,
+		Instrumenter:      agentMetrics,
+		Metrics:           agentMetrics.Handler(),
+	})
+	tracker := api.NewRequestTracker(router)
 
-	// Start HTTP server
 	addr := fmt.Sprintf(":%d", cfg.HTTPPort)
 	server := &http.Server{
 		Addr:         addr,
-		Handler:      router,
+		Handler:      tracker,
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 
-	logger.Info("Starting HTTP server", slog.String("address", addr))
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		return fmt.Errorf("server error: %w", err)
+	if cfg.TLS.Enabled {
+		tlsCfg, err := newServerTLSConfig(cfg.TLS)
+		if err != nil {
+			return fmt.Errorf("failed to configure TLS: %w", err)
+		}
+		server.TLSConfig = tlsCfg
 	}
 
+	// Background work outlives the shutdown signal: shutdown stops each part in order
+	// once the server is drained, so it must not see ctx being cancelled
+	jobsCtx := context.WithoutCancel(ctx)
+
+	// Start writing manager checks and health calls before anything produces them
+	checkWriter.Start()
+	healthService.Start()
+	if outbox != nil {
+		outbox.Start(jobsCtx)
+	}
+
+	// Start alerting before checks so that no transition is missed
+	if cfg.Alerting.Enabled {
+		alertService.Start(jobsCtx)
+	}
+
+	// Start background manager checks
+	if cfg.Scheduler.Enabled {
+		scheduler.Start(jobsCtx)
+	}
+
+	// Start downsampling and purging of raw history
+	if cfg.Retention.Enabled {
+		retention.Start(jobsCtx)
+	}
+	// Partitions of manager_checks are created ahead regardless of retention
+	partitions.Start(jobsCtx)
+	jobs := background{
+		scheduler:    scheduler,
+		alertService: alertService,
+		retention:    retention,
+		partitions:   partitions,
+		checkWriter:  checkWriter,
+		healthCalls:  healthService,
+		outbox:       outbox,
+	}
+
+	// Start HTTP server
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- serve(server, cfg.TLS, requireClientCert, logger)
+	}()
+
+	select {
+	case err := <-serverErr:
+		if err != nil {
+			shutdown(server, tracker, jobs, closeDB, cfg.ShutdownTimeoutSeconds, logger)
+			return fmt.Errorf("server error: %w", err)
+		}
+	case <-ctx.Done():
+		logger.Info("Shutdown signal received")
+	}
+
+	shutdown(server, tracker, jobs, closeDB, cfg.ShutdownTimeoutSeconds, logger)
 	return nil
 }
 
+// serve runs the HTTP or HTTPS listener until the server is shut down
+func serve(server *http.Server, tlsCfg config.TLSConfig, mtls bool, logger *slog.Logger) error {
+	var err error
+	if tlsCfg.Enabled {
+		logger.Info("Starting HTTPS server",
+			slog.String("address", server.Addr),
+			slog.Bool("mtls", mtls),
+		)
+		err = server.ListenAndServeTLS(tlsCfg.CertFile, tlsCfg.KeyFile)
+	} else {
+		logger.Info("Starting HTTP server", slog.String("address", server.Addr))
+		err = server.ListenAndServe()
+	}
+
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// managerTargets converts enabled manager targets from the config to service targets
+func managerTargets(cfg *config.Config, logger *slog.Logger) []service.ManagerTarget {
+	var targets []service.ManagerTarget
+	for _, t := range cfg.GetManagerTargets() {
+		if !t.IsEnabled() {
+			logger.Info("Manager disabled, skipping checks", slog.String("name", t.Name), slog.String("url", t.URL))
+			continue
+		}
+		targets = append(targets, service.ManagerTarget{
+			Name:            t.Name,
+			Type:            t.Type,
+			URL:             t.URL,
+			HealthPath:      t.HealthPath,
+			Method:          t.Method,
+			Headers:         t.Headers,
+			Body:            t.Body,
+			Timeout:         time.Duration(t.TimeoutSeconds) * time.Second,
+			ExpectedStatus:  t.ExpectedStatus,
+			ExpectedField:   t.ExpectedField,
+			ExpectedValue:   t.ExpectedValue,
+			Assertions:      assertions(t.Assertions),
+			MaxBodyBytes:    t.MaxBodyBytes,
+			MaxLatency:      time.Duration(t.MaxLatencyMillis) * time.Millisecond,
+			DegradedLatency: time.Duration(t.DegradedLatencyMillis) * time.Millisecond,
+			Weight:          t.Weight,
+			GRPCService:     t.GRPCService,
+			MinCertValidity: time.Duration(t.CertMinValidDays) * 24 * time.Hour,
+			ManagerTLS:      t.ManagerTLS,
+			Tags:            t.Tags,
+		})
+	}
+	return targets
+}
+
+// assertions converts configured response assertions to service assertions
+func assertions(cfgs []config.AssertionCfg) []service.Assertion {
+	result := make([]service.Assertion, 0, len(cfgs))
+	for _, a := range cfgs {
+		result = append(result, service.Assertion{
+			Source:   a.Source,
+			Path:     a.Path,
+			Op:       a.Op,
+			Value:    a.Value,
+			Severity: service.Status(a.Severity),
+		})
+	}
+	return result
+}
+
+// newAlertSinks creates a notification sink for every configured alert destination
+func newAlertSinks(cfg *config.Config) []service.AlertSink {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	var sinks []service.AlertSink
+	if cfg.Alerting.Webhook.URL != "" {
+		sinks = append(sinks, notifier.NewWebhookSink(client, cfg.Alerting.Webhook.URL))
+	}
+	if cfg.Alerting.Slack.WebhookURL != "" {
+		sinks = append(sinks, notifier.NewSlackSink(client, cfg.Alerting.Slack.WebhookURL))
+	}
+	if email := cfg.Alerting.Email; email.SMTPHost != "" && len(email.To) > 0 {
+		sinks = append(sinks, notifier.NewEmailSink(
+			email.SMTPHost,
+			email.SMTPPort,
+			email.SMTPUsername,
+			cfg.GetSMTPPassword(),
+			email.From,
+			email.To,
+		))
+	}
+	return sinks
+}
+
 // initLogger initializes the logger based on the environment
 func initLogger(env string) *slog.Logger {
 	var level slog.Level
@@ -97,6 +391,23 @@ func initLogger(env string) *slog.Logger {
 	return slog.New(handler)
 }
 
+// logConfigSources reports the config file in use and where each value came from
+func logConfigSources(cfg *config.Config, logger *slog.Logger) {
+	if cfg.Path() != "" {
+		logger.Info("Loaded config file", slog.String("path", cfg.Path()))
+	} else {
+		logger.Info("No config file found, using environment and defaults")
+	}
+
+	for _, src := range cfg.Sources() {
+		logger.Debug("config value",
+			slog.String("key", src.Key),
+			slog.String("source", string(src.Source)),
+			slog.String("origin", src.Origin),
+		)
+	}
+}
+
 // connectDB establishes a connection to PostgreSQL
 func connectDB(cfg *config.Config, logger *slog.Logger) (*sql.DB, error) {
 	dsn := cfg.GetDSN()
@@ -120,3 +431,35 @@ func connectDB(cfg *config.Config, logger *slog.Logger) (*sql.DB, error) {
 
 	return db, nil
 }
+
+// newHealthRegistry registers the liveness and readiness checks of the agent.
+// The expected schema version is the latest migration embedded in the binary.
+func newHealthRegistry(
+	cfg *config.Config,
+	storage *stg.Storage,
+	scheduler service.Scheduler,
+	logger *slog.Logger,
+) (*svc.HealthRegistry, error) {
+	migrations, err := migrator.LoadMigrations(migration.FS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load embedded migrations: %w", err)
+	}
+	var schemaVersion int64
+	if len(migrations) > 0 {
+		schemaVersion = migrations[len(migrations)-1].Version
+	}
+
+	registry := svc.NewHealthRegistry(time.Duration(cfg.Health.CheckTimeoutMillis)*time.Millisecond, logger)
+	registry.Register("scheduler", svc.LivenessCheck, svc.SchedulerCheck(
+		scheduler,
+		time.Duration(cfg.Health.SchedulerMaxAgeSeconds)*time.Second,
+		time.Now(),
+	))
+	registry.Register("database", svc.ReadinessCheck, svc.DatabaseCheck(storage))
+	registry.Register("migrations", svc.ReadinessCheck, svc.MigrationCheck(storage, schemaVersion))
+	registry.Register("disk", svc.ReadinessCheck, svc.DiskCheck(
+		cfg.Health.DiskPath,
+		uint64(max(cfg.Health.MinFreeDiskMB, 0))<<20, // nolint:gosec // negative values are clamped
+	))
+	return registry, nil
+}
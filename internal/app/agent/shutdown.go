@@ -0,0 +1,86 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	api "github.com/Shemistan/agent/internal/api/agent"
+	svc "github.com/Shemistan/agent/internal/service/agent"
+	stg "github.com/Shemistan/agent/internal/storage/agent"
+)
+
+// background groups the background services of the agent that are stopped on shutdown
+type background struct {
+	scheduler    *svc.CheckScheduler
+	alertService *svc.AlertService
+	retention    *svc.RetentionJob
+	partitions   *svc.PartitionMaintainer
+	checkWriter  *stg.BatchWriter
+	healthCalls  *svc.HealthService
+	outbox       *stg.Outbox // nil when disabled
+}
+
+// shutdown stops the agent in order: stop accepting requests and drain active
+// handlers, stop background checks, flush buffered checks and health calls, stop alerting,
+// retention and partition maintenance, then close the database.
+// All steps share one grace period; a summary of what was drained is logged at the end.
+func shutdown(
+	server *http.Server,
+	tracker *api.RequestTracker,
+	jobs background,
+	closeDB func() error,
+	timeoutSeconds int,
+	logger *slog.Logger,
+) {
+	startedAt := time.Now()
+	grace := time.Duration(timeoutSeconds) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+
+	inFlight := tracker.Active()
+	completedBefore := tracker.Completed()
+	logger.Info("Shutting down",
+		slog.Int64("in_flight_requests", inFlight),
+		slog.Duration("grace_period", grace),
+	)
+
+	// Stop accepting new requests and wait for active handlers
+	forced := false
+	if err := server.Shutdown(ctx); err != nil {
+		forced = true
+		logger.Warn("grace period expired, closing remaining connections", slog.String("error", err.Error()))
+		if cerr := server.Close(); cerr != nil {
+			logger.Warn("failed to close server", slog.String("error", cerr.Error()))
+		}
+	}
+	drained := tracker.Completed() - completedBefore
+
+	// Stop background work before its storage goes away
+	jobs.scheduler.Stop(ctx)
+	jobs.checkWriter.Stop(ctx) // flush buffered checks once nothing produces them anymore
+	if jobs.outbox != nil {
+		jobs.outbox.Stop() // checks spooled by the final flush are replayed on the next start
+	}
+	jobs.healthCalls.Stop(ctx)
+	jobs.alertService.Stop(ctx)
+	jobs.retention.Stop()
+	jobs.partitions.Stop()
+
+	dbClosed := true
+	if err := closeDB(); err != nil {
+		dbClosed = false
+		logger.Warn("failed to close database", slog.String("error", err.Error()))
+	}
+
+	logger.Info("Shutdown complete",
+		slog.Int64("in_flight_at_signal", inFlight),
+		slog.Int64("drained_requests", drained),
+		slog.Int64("abandoned_requests", tracker.Active()),
+		slog.Bool("forced", forced),
+		slog.Int64("scheduler_runs", jobs.scheduler.Status().Runs),
+		slog.Bool("db_closed", dbClosed),
+		slog.Duration("duration", time.Since(startedAt)),
+	)
+}
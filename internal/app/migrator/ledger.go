@@ -0,0 +1,235 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// migrationTimeout bounds the execution time of a single transactional migration.
+// Statements of no-transaction migrations are not bounded: they build indexes and validate
+// constraints on whole tables, and cancelling them would only leave invalid objects behind.
+const migrationTimeout = 5 * time.Minute
+
+// appliedMigration represents a row of the schema_migrations ledger
+type appliedMigration struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// ensureLedger creates the schema_migrations table if it does not exist yet
+func ensureLedger(ctx context.Context, db *sql.DB) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name TEXT NOT NULL,
+			checksum TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`
+	if _, err := db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+// loadApplied returns applied migrations keyed by version
+func loadApplied(ctx context.Context, db *sql.DB) (map[int64]appliedMigration, error) {
+	query := `
+		SELECT version, name, checksum, applied_at
+		FROM schema_migrations
+		ORDER BY version
+	`
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("load schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]appliedMigration)
+	for rows.Next() {
+		var m appliedMigration
+		if err := rows.Scan(&m.Version, &m.Name, &m.Checksum, &m.AppliedAt); err != nil {
+			return nil, fmt.Errorf("scan schema_migrations: %w", err)
+		}
+		applied[m.Version] = m
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("load schema_migrations: %w", err)
+	}
+	return applied, nil
+}
+
+// verifyChecksums refuses to continue if an applied migration file was modified afterwards
+func verifyChecksums(migrations []Migration, applied map[int64]appliedMigration, logger *slog.Logger) error {
+	known := make(map[int64]bool, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = true
+		a, ok := applied[m.Version]
+		if !ok {
+			continue
+		}
+		if a.Checksum != m.Checksum {
+			return fmt.Errorf(
+				"checksum mismatch for migration %d (%s): file was modified after being applied at %s",
+				m.Version, m.Name, a.AppliedAt.Format(time.RFC3339),
+			)
+		}
+	}
+
+	for version, a := range applied {
+		if !known[version] {
+			logger.Warn("applied migration is missing from migration directory",
+				slog.Int64("version", version),
+				slog.String("name", a.Name),
+			)
+		}
+	}
+	return nil
+}
+
+// applyMigration runs a migration and records it in the ledger within one transaction.
+// Migrations with the no-transaction directive run statement by statement instead.
+func applyMigration(ctx context.Context, db *sql.DB, m Migration) error {
+	if hasDirective(m.UpSQL, noTransactionDirective) {
+		query := `
+			INSERT INTO schema_migrations (version, name, checksum)
+			VALUES ($1, $2, $3)
+		`
+		return execWithoutTransaction(ctx, db, m.UpSQL, query, m.Version, m.Name, m.Checksum)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, migrationTimeout)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx, m.UpSQL); err != nil {
+		return fmt.Errorf("execute: %w", err)
+	}
+
+	query := `
+		INSERT INTO schema_migrations (version, name, checksum)
+		VALUES ($1, $2, $3)
+	`
+	if _, err := tx.ExecContext(ctx, query, m.Version, m.Name, m.Checksum); err != nil {
+		return fmt.Errorf("record in schema_migrations: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// rollbackMigration runs a down migration and removes it from the ledger within one transaction
+func rollbackMigration(ctx context.Context, db *sql.DB, m Migration) error {
+	if !m.HasDown {
+		return fmt.Errorf("migration has no down file")
+	}
+	if hasDirective(m.DownSQL, noTransactionDirective) {
+		query := `
+			DELETE FROM schema_migrations
+			WHERE version = $1
+		`
+		return execWithoutTransaction(ctx, db, m.DownSQL, query, m.Version)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, migrationTimeout)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx, m.DownSQL); err != nil {
+		return fmt.Errorf("execute: %w", err)
+	}
+
+	query := `
+		DELETE FROM schema_migrations
+		WHERE version = $1
+	`
+	if _, err := tx.ExecContext(ctx, query, m.Version); err != nil {
+		return fmt.Errorf("remove from schema_migrations: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// execWithoutTransaction runs the statements of script one by one on a single connection,
+// each in its own implicit transaction, and then updates the ledger with ledgerQuery.
+// Statements run without a deadline of their own, only ctx bounds them, so that long index builds
+// on large tables can complete. A failure leaves the statements before it applied, so such scripts
+// must be safe to run again.
+func execWithoutTransaction(ctx context.Context, db *sql.DB, script, ledgerQuery string, args ...any) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("get connection: %w", err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	for i, statement := range splitStatements(script) {
+		statements := []string{statement}
+		if hasDirective(statement, gexecDirective) {
+			statements, err = generateStatements(ctx, conn, statement)
+			if err != nil {
+				return fmt.Errorf("execute statement %d: %w", i+1, err)
+			}
+		}
+
+		for _, statement := range statements {
+			if _, err := conn.ExecContext(ctx, statement); err != nil {
+				return fmt.Errorf("execute statement %d: %w", i+1, err)
+			}
+		}
+	}
+
+	ledgerCtx, cancel := context.WithTimeout(ctx, migrationTimeout)
+	defer cancel()
+	if _, err := conn.ExecContext(ledgerCtx, ledgerQuery, args...); err != nil {
+		return fmt.Errorf("update schema_migrations: %w", err)
+	}
+	return nil
+}
+
+// generateStatements runs query and returns the statements it produced, one per row
+func generateStatements(ctx context.Context, conn *sql.Conn, query string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, migrationTimeout)
+	defer cancel()
+
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var statements []string
+	for rows.Next() {
+		var statement string
+		if err := rows.Scan(&statement); err != nil {
+			return nil, err
+		}
+		statements = append(statements, statement)
+	}
+	return statements, rows.Err()
+}
@@ -0,0 +1,154 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// advisoryLockKey is the Postgres advisory lock key shared by all migrator instances
+const advisoryLockKey int64 = 0x61676e746d6967 // "agntmig"
+
+const (
+	lockPollInterval = time.Second
+	lockLogInterval  = 10 * time.Second
+)
+
+// lockHolder describes the session currently holding the migration lock
+type lockHolder struct {
+	PID             int64
+	ApplicationName string
+	ClientAddr      string
+	BackendStart    time.Time
+}
+
+// acquireLock takes the session-level migration advisory lock on a dedicated
+// connection, waiting up to timeout. The returned function releases the lock.
+func acquireLock(ctx context.Context, db *sql.DB, timeout time.Duration, logger *slog.Logger) (func(), error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("open lock connection: %w", err)
+	}
+
+	var pid int64
+	if err := conn.QueryRowContext(ctx, `SELECT pg_backend_pid()`).Scan(&pid); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("get backend pid: %w", err)
+	}
+
+	// Make this session recognisable to other waiting migrators
+	if _, err := conn.ExecContext(ctx, `SELECT set_config('application_name', $1, false)`, applicationName()); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("set application name: %w", err)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	startedAt := time.Now()
+	var lastLog time.Time
+	for {
+		var locked bool
+		if err := conn.QueryRowContext(waitCtx, `SELECT pg_try_advisory_lock($1)`, advisoryLockKey).Scan(&locked); err != nil {
+			_ = conn.Close()
+			if errors.Is(waitCtx.Err(), context.DeadlineExceeded) {
+				return nil, fmt.Errorf("timed out after %s waiting for migration lock", timeout)
+			}
+			return nil, fmt.Errorf("try advisory lock: %w", err)
+		}
+
+		if locked {
+			logger.Info("Acquired migration lock",
+				slog.Int64("pid", pid),
+				slog.Duration("waited", time.Since(startedAt).Round(time.Millisecond)),
+			)
+			return func() { releaseLock(conn, logger) }, nil
+		}
+
+		if time.Since(lastLog) >= lockLogInterval {
+			lastLog = time.Now()
+			logWaiting(waitCtx, conn, pid, time.Since(startedAt), logger)
+		}
+
+		select {
+		case <-waitCtx.Done():
+			_ = conn.Close()
+			return nil, fmt.Errorf("timed out after %s waiting for migration lock", timeout)
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// releaseLock unlocks the advisory lock and returns the connection to the pool
+func releaseLock(conn *sql.Conn, logger *slog.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, advisoryLockKey); err != nil {
+		logger.Warn("failed to release migration lock", slog.String("error", err.Error()))
+	}
+	if err := conn.Close(); err != nil {
+		logger.Warn("failed to close lock connection", slog.String("error", err.Error()))
+	}
+	logger.Info("Released migration lock")
+}
+
+// logWaiting reports which session holds the lock this migrator is waiting for
+func logWaiting(ctx context.Context, conn *sql.Conn, pid int64, waited time.Duration, logger *slog.Logger) {
+	holder, err := findLockHolder(ctx, conn)
+	if err != nil {
+		logger.Warn("waiting for migration lock, holder unknown",
+			slog.Int64("pid", pid),
+			slog.Duration("waited", waited.Round(time.Second)),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	logger.Info("Waiting for migration lock",
+		slog.Int64("pid", pid),
+		slog.String("application_name", applicationName()),
+		slog.Int64("holder_pid", holder.PID),
+		slog.String("holder_application_name", holder.ApplicationName),
+		slog.String("holder_client_addr", holder.ClientAddr),
+		slog.Time("holder_connected_at", holder.BackendStart),
+		slog.Duration("waited", waited.Round(time.Second)),
+	)
+}
+
+// findLockHolder looks up the session holding the migration advisory lock.
+// A bigint advisory key is stored in pg_locks split into classid (high) and objid (low).
+func findLockHolder(ctx context.Context, conn *sql.Conn) (lockHolder, error) {
+	query := `
+		SELECT a.pid, COALESCE(a.application_name, ''), COALESCE(host(a.client_addr), 'local'), a.backend_start
+		FROM pg_locks l
+		JOIN pg_stat_activity a ON a.pid = l.pid
+		WHERE l.locktype = 'advisory'
+		  AND l.granted
+		  AND l.classid::bigint = $1
+		  AND l.objid::bigint = $2
+		  AND l.objsubid = 1
+		LIMIT 1
+	`
+	var holder lockHolder
+	err := conn.QueryRowContext(ctx, query, advisoryLockKey>>32, advisoryLockKey&0xffffffff).Scan(
+		&holder.PID, &holder.ApplicationName, &holder.ClientAddr, &holder.BackendStart,
+	)
+	if err != nil {
+		return lockHolder{}, fmt.Errorf("find lock holder: %w", err)
+	}
+	return holder, nil
+}
+
+// applicationName identifies this migrator process in pg_stat_activity
+func applicationName() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("migrator@%s/%d", host, os.Getpid())
+}
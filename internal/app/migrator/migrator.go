@@ -6,26 +6,55 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
-	"path/filepath"
-	"sort"
-	"strings"
 	"time"
 
 	"github.com/Shemistan/agent/internal/config"
 	_ "github.com/lib/pq" // nolint:gci
 )
 
-// Run runs the database migrations
-func Run(migrationDir string) error {
+// Options describes a single migrator invocation
+type Options struct {
+	ConfigPath   string
+	MigrationDir string
+	Command      string // one of up, down, goto, status or redo
+	Args         []string
+}
+
+// ParseArgs splits positional arguments into the migration directory, the command and its arguments.
+// For compatibility with `migrator <dir>`, a first argument that names an existing directory
+// is taken as the migration directory and the command defaults to up.
+func ParseArgs(dir string, args []string) (string, string, []string) {
+	if len(args) > 0 {
+		if info, err := os.Stat(args[0]); err == nil && info.IsDir() {
+			dir, args = args[0], args[1:]
+		}
+	}
+	if len(args) == 0 {
+		return dir, CommandUp, nil
+	}
+	return dir, args[0], args[1:]
+}
+
+// Run executes a migrator command against the database
+func Run(opts Options) error {
+	migrations, err := LoadMigrations(os.DirFS(opts.MigrationDir))
+	if err != nil {
+		return err
+	}
+
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := config.Load(opts.ConfigPath)
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
 	// Initialize logger
 	logger := initLogger(cfg.ServiceEnv)
-	logger.Info("Starting migrator", slog.String("service_name", cfg.ServiceName))
+	logger.Info("Starting migrator",
+		slog.String("service_name", cfg.ServiceName),
+		slog.String("config_file", cfg.Path()),
+		slog.String("command", opts.Command),
+	)
 
 	// Connect to PostgreSQL
 	dsn := cfg.GetDSN()
@@ -54,58 +83,26 @@ func Run(migrationDir string) error {
 
 	logger.Info("Connected to database")
 
-	// Run migrations
-	if err := runMigrations(db, migrationDir, logger); err != nil {
-		return err
-	}
-
-	logger.Info("Migrations completed successfully")
-	return nil
-}
-
-// runMigrations executes all SQL migrations in order
-func runMigrations(db *sql.DB, migrationDir string, logger *slog.Logger) error {
-	// Read migration directory
-	files, err := os.ReadDir(migrationDir)
-	if err != nil {
-		return fmt.Errorf("failed to read migration directory: %w", err)
-	}
-
-	// Filter and sort .sql files
-	var sqlFiles []string
-	for _, file := range files {
-		if !file.IsDir() && strings.HasSuffix(file.Name(), ".sql") {
-			sqlFiles = append(sqlFiles, file.Name())
+	// Serialize concurrent migrators; status is read-only and does not need the lock
+	if opts.Command != CommandStatus {
+		lockTimeout := time.Duration(cfg.Migrator.LockTimeoutSeconds) * time.Second
+		unlock, err := acquireLock(context.Background(), db, lockTimeout, logger)
+		if err != nil {
+			return err
 		}
+		defer unlock()
 	}
 
-	if len(sqlFiles) == 0 {
-		logger.Info("No migration files found")
-		return nil
+	r, err := newRunner(context.Background(), db, migrations, os.Stdout, logger)
+	if err != nil {
+		return err
 	}
 
-	sort.Strings(sqlFiles)
-
-	// Execute each migration file
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	for _, sqlFile := range sqlFiles {
-		filePath := filepath.Join(migrationDir, sqlFile)
-		logger.Info("Running migration", slog.String("file", sqlFile))
-
-		content, err := os.ReadFile(filePath)
-		if err != nil {
-			return fmt.Errorf("failed to read migration file %s: %w", sqlFile, err)
-		}
-
-		if _, err := db.ExecContext(ctx, string(content)); err != nil {
-			return fmt.Errorf("failed to execute migration %s: %w", sqlFile, err)
-		}
-
-		logger.Info("Migration completed", slog.String("file", sqlFile))
+	if err := r.execute(context.Background(), opts.Command, opts.Args); err != nil {
+		return err
 	}
 
+	logger.Info("Migrator finished successfully", slog.String("command", opts.Command))
 	return nil
 }
 
@@ -0,0 +1,40 @@
+package migrator
+
+import (
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name        string
+		args        []string
+		wantDir     string
+		wantCommand string
+		wantArgs    []string
+	}{
+		{name: "no arguments", args: nil, wantDir: "migration", wantCommand: CommandUp},
+		{name: "command", args: []string{"down", "2"}, wantDir: "migration", wantCommand: CommandDown, wantArgs: []string{"2"}},
+		{name: "legacy directory", args: []string{dir}, wantDir: dir, wantCommand: CommandUp},
+		{name: "directory and command", args: []string{dir, "status"}, wantDir: dir, wantCommand: CommandStatus},
+		{name: "missing directory is a command", args: []string{"no-such-dir"}, wantDir: "migration", wantCommand: "no-such-dir"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotDir, gotCommand, gotArgs := ParseArgs("migration", tt.args)
+			if gotDir != tt.wantDir || gotCommand != tt.wantCommand {
+				t.Fatalf("Expected dir %q and command %q, got %q and %q", tt.wantDir, tt.wantCommand, gotDir, gotCommand)
+			}
+			if len(gotArgs) != len(tt.wantArgs) {
+				t.Fatalf("Expected args %v, got %v", tt.wantArgs, gotArgs)
+			}
+			for i := range gotArgs {
+				if gotArgs[i] != tt.wantArgs[i] {
+					t.Fatalf("Expected args %v, got %v", tt.wantArgs, gotArgs)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// Supported migrator commands
+const (
+	CommandUp     = "up"
+	CommandDown   = "down"
+	CommandGoto   = "goto"
+	CommandStatus = "status"
+	CommandRedo   = "redo"
+)
+
+// runner executes migrator commands against the schema_migrations ledger
+type runner struct {
+	db         *sql.DB
+	migrations []Migration
+	applied    map[int64]appliedMigration
+	out        io.Writer
+	logger     *slog.Logger
+}
+
+// newRunner prepares the ledger and verifies that applied files were not modified
+func newRunner(ctx context.Context, db *sql.DB, migrations []Migration, out io.Writer, logger *slog.Logger) (*runner, error) {
+	if err := ensureLedger(ctx, db); err != nil {
+		return nil, err
+	}
+
+	applied, err := loadApplied(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifyChecksums(migrations, applied, logger); err != nil {
+		return nil, err
+	}
+
+	return &runner{
+		db:         db,
+		migrations: migrations,
+		applied:    applied,
+		out:        out,
+		logger:     logger,
+	}, nil
+}
+
+// execute dispatches a command with its arguments
+func (r *runner) execute(ctx context.Context, command string, args []string) error {
+	switch command {
+	case CommandUp:
+		n, err := optionalCount(args, 0)
+		if err != nil {
+			return err
+		}
+		return r.up(ctx, n)
+	case CommandDown:
+		n, err := optionalCount(args, 1)
+		if err != nil {
+			return err
+		}
+		return r.down(ctx, n)
+	case CommandGoto:
+		if len(args) != 1 {
+			return fmt.Errorf("goto requires exactly one VERSION argument")
+		}
+		version, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil || version < 0 {
+			return fmt.Errorf("invalid version %q", args[0])
+		}
+		return r.gotoVersion(ctx, version)
+	case CommandStatus:
+		return r.status()
+	case CommandRedo:
+		return r.redo(ctx)
+	default:
+		return fmt.Errorf("unknown command %q (expected up, down, goto, status or redo)", command)
+	}
+}
+
+// up applies the next n pending migrations, or all of them when n is 0
+func (r *runner) up(ctx context.Context, n int) error {
+	pending := r.pending()
+	if n > 0 && n < len(pending) {
+		pending = pending[:n]
+	}
+
+	if len(pending) == 0 {
+		r.logger.Info("No pending migrations")
+		return nil
+	}
+
+	for _, m := range pending {
+		if err := r.apply(ctx, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply applies a single migration and records it as applied
+func (r *runner) apply(ctx context.Context, m Migration) error {
+	r.logger.Info("Running migration", slog.String("file", m.Name))
+	if err := applyMigration(ctx, r.db, m); err != nil {
+		return fmt.Errorf("failed to apply migration %s: %w", m.Name, err)
+	}
+	r.applied[m.Version] = appliedMigration{Version: m.Version, Name: m.Name, Checksum: m.Checksum, AppliedAt: time.Now()}
+	r.logger.Info("Migration completed", slog.String("file", m.Name))
+	return nil
+}
+
+// down rolls back the last n applied migrations
+func (r *runner) down(ctx context.Context, n int) error {
+	versions := r.appliedVersions()
+	if n > len(versions) {
+		n = len(versions)
+	}
+
+	if n == 0 {
+		r.logger.Info("No applied migrations to roll back")
+		return nil
+	}
+
+	for _, version := range versions[len(versions)-n:] {
+		if r.find(version) == nil {
+			return fmt.Errorf("applied migration %d (%s) has no file, cannot roll back", version, r.applied[version].Name)
+		}
+	}
+
+	for i := len(versions) - 1; i >= len(versions)-n; i-- {
+		m := r.find(versions[i])
+		r.logger.Info("Rolling back migration", slog.String("file", m.Name))
+		if err := rollbackMigration(ctx, r.db, *m); err != nil {
+			return fmt.Errorf("failed to roll back migration %s: %w", m.Name, err)
+		}
+		delete(r.applied, m.Version)
+		r.logger.Info("Rollback completed", slog.String("file", m.Name))
+	}
+	return nil
+}
+
+// gotoVersion applies or rolls back migrations until exactly the migrations
+// up to and including version are applied. Version 0 rolls back everything.
+func (r *runner) gotoVersion(ctx context.Context, version int64) error {
+	if version != 0 && r.find(version) == nil {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+
+	rollback := 0
+	for _, applied := range r.appliedVersions() {
+		if applied > version {
+			rollback++
+		}
+	}
+	if err := r.down(ctx, rollback); err != nil {
+		return err
+	}
+
+	apply := 0
+	for _, m := range r.pending() {
+		if m.Version <= version {
+			apply++
+		}
+	}
+	if apply == 0 {
+		return nil
+	}
+	return r.up(ctx, apply)
+}
+
+// redo rolls back the last applied migration and applies only that migration again,
+// leaving pending migrations with lower versions untouched
+func (r *runner) redo(ctx context.Context) error {
+	versions := r.appliedVersions()
+	if len(versions) == 0 {
+		r.logger.Info("No applied migrations to redo")
+		return nil
+	}
+
+	last := versions[len(versions)-1]
+	if err := r.down(ctx, 1); err != nil {
+		return err
+	}
+	return r.apply(ctx, *r.find(last))
+}
+
+// status prints every known migration with its ledger state
+func (r *runner) status() error {
+	w := tabwriter.NewWriter(r.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT\tDOWN")
+	for _, m := range r.migrations {
+		state, appliedAt := "pending", "-"
+		if a, ok := r.applied[m.Version]; ok {
+			state, appliedAt = "applied", a.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%t\n", m.Version, m.Name, state, appliedAt, m.HasDown)
+	}
+	return w.Flush()
+}
+
+// pending returns migrations that are not in the ledger, in version order
+func (r *runner) pending() []Migration {
+	var pending []Migration
+	for _, m := range r.migrations {
+		if _, ok := r.applied[m.Version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	return pending
+}
+
+// appliedVersions returns versions recorded in the ledger in ascending order
+func (r *runner) appliedVersions() []int64 {
+	versions := make([]int64, 0, len(r.applied))
+	for version := range r.applied {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions
+}
+
+func (r *runner) find(version int64) *Migration {
+	for i := range r.migrations {
+		if r.migrations[i].Version == version {
+			return &r.migrations[i]
+		}
+	}
+	return nil
+}
+
+// optionalCount parses an optional positive count argument
+func optionalCount(args []string, fallback int) (int, error) {
+	if len(args) == 0 {
+		return fallback, nil
+	}
+	if len(args) > 1 {
+		return 0, fmt.Errorf("expected at most one N argument, got %d", len(args))
+	}
+
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid count %q", args[0])
+	}
+	return n, nil
+}
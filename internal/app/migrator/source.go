@@ -0,0 +1,204 @@
+package migrator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	// noTransactionDirective in the leading comments of a script makes the migrator run its statements
+	// one by one outside of a transaction, e.g. for CREATE INDEX CONCURRENTLY
+	noTransactionDirective = "-- migrate:no-transaction"
+	// gexecDirective in the leading comments of a query in a no-transaction script makes the migrator
+	// execute every row of its result as a statement, like \gexec in psql
+	gexecDirective = "-- migrate:gexec"
+)
+
+// Migration represents a single versioned migration with an optional down script.
+// Files are named <version>_<name>.up.sql and <version>_<name>.down.sql;
+// a plain <version>_<name>.sql file is treated as an up migration without a down.
+type Migration struct {
+	Version  int64
+	Name     string // up file name, e.g. "001_init_health.up.sql"
+	UpSQL    string
+	DownSQL  string
+	HasDown  bool
+	Checksum string // hex encoded SHA-256 of UpSQL
+}
+
+// LoadMigrations reads all .sql files from fsys and returns them sorted by version
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration directory: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	downFiles := make(map[int64]string)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+
+		version, err := parseVersion(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration file %s: %w", entry.Name(), err)
+		}
+
+		if strings.HasSuffix(entry.Name(), ".down.sql") {
+			if other, ok := downFiles[version]; ok {
+				return nil, fmt.Errorf("duplicate down migration version %d: %s and %s", version, other, entry.Name())
+			}
+			downFiles[version] = entry.Name()
+			m := migrationFor(byVersion, version)
+			m.DownSQL = string(content)
+			m.HasDown = true
+			continue
+		}
+
+		m := migrationFor(byVersion, version)
+		if m.Name != "" {
+			return nil, fmt.Errorf("duplicate migration version %d: %s and %s", version, m.Name, entry.Name())
+		}
+		m.Name = entry.Name()
+		m.UpSQL = string(content)
+		m.Checksum = checksum(content)
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for version, m := range byVersion {
+		if m.Name == "" {
+			return nil, fmt.Errorf("down migration %s has no matching up migration", downFiles[version])
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+func migrationFor(byVersion map[int64]*Migration, version int64) *Migration {
+	m, ok := byVersion[version]
+	if !ok {
+		m = &Migration{Version: version}
+		byVersion[version] = m
+	}
+	return m
+}
+
+// parseVersion extracts the numeric prefix of a migration file name
+func parseVersion(name string) (int64, error) {
+	prefix, _, ok := strings.Cut(name, "_")
+	if !ok {
+		return 0, fmt.Errorf("invalid migration file name %s: expected <version>_<name>.sql", name)
+	}
+
+	version, err := strconv.ParseInt(prefix, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("invalid migration version in file name %s", name)
+	}
+	return version, nil
+}
+
+func checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// hasDirective reports whether the leading comments of script contain directive
+func hasDirective(script, directive string) bool {
+	for _, line := range strings.Split(script, "\n") {
+		line = strings.TrimSpace(line)
+		if line == directive {
+			return true
+		}
+		if line != "" && !strings.HasPrefix(line, "--") {
+			return false
+		}
+	}
+	return false
+}
+
+// splitStatements splits script at semicolons that are outside of quotes, comments and
+// dollar-quoted bodies. Statements consisting only of comments are dropped.
+func splitStatements(script string) []string {
+	var statements []string
+	start := 0
+	hasCode := false
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case strings.HasPrefix(script[i:], "--"):
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				end = len(script) - i
+			}
+			i += end
+			continue
+		case strings.HasPrefix(script[i:], "/*"):
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				end = len(script) - i - 3
+			}
+			i += end + 3
+			continue
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(script[i+1:], c)
+			if end < 0 {
+				end = len(script) - i - 1
+			}
+			i += end + 1
+		case c == '$':
+			if tag := dollarTag(script[i:]); tag != "" {
+				end := strings.Index(script[i+len(tag):], tag)
+				if end < 0 {
+					end = len(script) - i - len(tag)
+				}
+				i += len(tag) + end + len(tag) - 1
+			}
+		case c == ';':
+			if hasCode {
+				statements = append(statements, strings.TrimSpace(script[start:i]))
+			}
+			start = i + 1
+			hasCode = false
+			continue
+		}
+		if !unicode.IsSpace(rune(c)) {
+			hasCode = true
+		}
+	}
+	if hasCode {
+		statements = append(statements, strings.TrimSpace(script[start:]))
+	}
+	return statements
+}
+
+// dollarTag returns the opening $tag$ of a dollar-quoted string at the start of s, or ""
+func dollarTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '$':
+			return s[:i+1]
+		case c == '_' || unicode.IsLetter(rune(c)):
+		case unicode.IsDigit(rune(c)) && i > 1:
+		default:
+			return ""
+		}
+	}
+	return ""
+}
@@ -0,0 +1,148 @@
+package migrator
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestLoadMigrations_SortsByVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"010_add_index.sql":     {Data: []byte("CREATE INDEX x ON t(c);")},
+		"002_init_checks.sql":   {Data: []byte("CREATE TABLE t (c INT);")},
+		"001_init_health.sql":   {Data: []byte("CREATE TABLE h (c INT);")},
+		"README.md":             {Data: []byte("not a migration")},
+		"nested/003_ignore.sql": {Data: []byte("SELECT 1;")},
+	}
+
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		t.Fatalf("LoadMigrations failed: %v", err)
+	}
+
+	if len(migrations) != 3 {
+		t.Fatalf("Expected 3 migrations, got %d", len(migrations))
+	}
+
+	expected := []int64{1, 2, 10}
+	for i, m := range migrations {
+		if m.Version != expected[i] {
+			t.Fatalf("Expected version %d at %d, got %d", expected[i], i, m.Version)
+		}
+		if m.Checksum == "" {
+			t.Fatalf("Expected checksum for %s", m.Name)
+		}
+	}
+}
+
+func TestLoadMigrations_RejectsDuplicateVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"001_a.sql":  {Data: []byte("SELECT 1;")},
+		"0001_b.sql": {Data: []byte("SELECT 2;")},
+	}
+
+	if _, err := LoadMigrations(fsys); err == nil {
+		t.Fatal("Expected error for duplicate version")
+	}
+}
+
+func TestVerifyChecksums_DetectsModifiedFile(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	migrations := []Migration{
+		{Version: 1, Name: "001_a.sql", Checksum: checksum([]byte("SELECT 1;"))},
+	}
+
+	applied := map[int64]appliedMigration{
+		1: {Version: 1, Name: "001_a.sql", Checksum: checksum([]byte("SELECT 1;")), AppliedAt: time.Now()},
+	}
+	if err := verifyChecksums(migrations, applied, logger); err != nil {
+		t.Fatalf("Expected matching checksums to pass, got %v", err)
+	}
+
+	applied[1] = appliedMigration{Version: 1, Name: "001_a.sql", Checksum: checksum([]byte("SELECT 2;")), AppliedAt: time.Now()}
+	if err := verifyChecksums(migrations, applied, logger); err == nil {
+		t.Fatal("Expected checksum mismatch error")
+	}
+}
+
+func TestLoadMigrations_PairsUpAndDown(t *testing.T) {
+	fsys := fstest.MapFS{
+		"001_init.up.sql":   {Data: []byte("CREATE TABLE t (c INT);")},
+		"001_init.down.sql": {Data: []byte("DROP TABLE t;")},
+		"002_legacy.sql":    {Data: []byte("CREATE TABLE l (c INT);")},
+	}
+
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		t.Fatalf("LoadMigrations failed: %v", err)
+	}
+
+	if len(migrations) != 2 {
+		t.Fatalf("Expected 2 migrations, got %d", len(migrations))
+	}
+
+	if !migrations[0].HasDown || migrations[0].DownSQL != "DROP TABLE t;" {
+		t.Fatalf("Expected down SQL for version 1, got %+v", migrations[0])
+	}
+
+	if migrations[0].Checksum != checksum([]byte("CREATE TABLE t (c INT);")) {
+		t.Fatal("Expected checksum to cover only the up file")
+	}
+
+	if migrations[1].HasDown {
+		t.Fatal("Expected legacy migration without down file")
+	}
+}
+
+func TestLoadMigrations_RejectsOrphanDown(t *testing.T) {
+	fsys := fstest.MapFS{
+		"001_init.down.sql": {Data: []byte("DROP TABLE t;")},
+	}
+
+	if _, err := LoadMigrations(fsys); err == nil {
+		t.Fatal("Expected error for down migration without up")
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := `-- migrate:no-transaction
+-- leading comment; not a statement
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_a ON a(c);
+
+DO $$
+BEGIN
+    RAISE NOTICE 'a;b';
+END $$;
+/* block; comment */
+SELECT $tag$x;y$tag$, 'it''s;', "odd;name" FROM t WHERE c = $1
+`
+
+	statements := splitStatements(script)
+	if len(statements) != 3 {
+		t.Fatalf("Expected 3 statements, got %d: %q", len(statements), statements)
+	}
+	if !strings.HasSuffix(statements[0], "CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_a ON a(c)") {
+		t.Fatalf("Unexpected first statement: %q", statements[0])
+	}
+	if !strings.HasPrefix(statements[1], "DO $$") || !strings.HasSuffix(statements[1], "END $$") {
+		t.Fatalf("Expected the DO block to stay in one piece, got %q", statements[1])
+	}
+	if !strings.HasSuffix(statements[2], `FROM t WHERE c = $1`) {
+		t.Fatalf("Unexpected last statement: %q", statements[2])
+	}
+}
+
+func TestHasDirective(t *testing.T) {
+	if !hasDirective("-- Build the index online\n-- migrate:no-transaction\nCREATE INDEX CONCURRENTLY i ON t(c);", noTransactionDirective) {
+		t.Fatal("Expected directive in leading comments to be detected")
+	}
+	if hasDirective("CREATE TABLE t (c INT);\n-- migrate:no-transaction\n", noTransactionDirective) {
+		t.Fatal("Expected directive after the first statement to be ignored")
+	}
+	if !hasDirective("\n-- Drop leftovers\n-- migrate:gexec\nSELECT format('DROP INDEX %I', relname) FROM pg_class", gexecDirective) {
+		t.Fatal("Expected gexec directive of a split statement to be detected")
+	}
+}
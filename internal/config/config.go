@@ -1,14 +1,41 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/BurntSushi/toml"
 	"github.com/joho/godotenv"
 )
 
+// DefaultConfigPath is the TOML file read when neither a flag nor CONFIG_PATH is given
+const DefaultConfigPath = "app.toml"
+
+// Source identifies where a configuration value came from
+type Source string
+
+const (
+	// SourceDefault means the value was neither in the file nor in the environment
+	SourceDefault Source = "default"
+	// SourceFile means the value was read from the TOML file
+	SourceFile Source = "file"
+	// SourceEnv means the value was overridden by an environment variable
+	SourceEnv Source = "env"
+)
+
+// ValueSource describes the origin of a single configuration value
+type ValueSource struct {
+	Key    string // dotted TOML key, e.g. "database.host"
+	Source Source
+	Origin string // file path or environment variable name, empty for defaults
+}
+
 // DatabaseCfg represents database configuration
 type DatabaseCfg struct {
 	Host    string `toml:"host"`
@@ -26,110 +53,524 @@ type TLSConfig struct {
 	CAFile   string `toml:"ca_file"`
 }
 
-// ManagerCfg represents manager service configuration
+// ManagerTLSCfg represents outbound TLS configuration for manager checks
+type ManagerTLSCfg struct {
+	CAFile     string `toml:"ca_file"`
+	CertFile   string `toml:"cert_file"`
+	KeyFile    string `toml:"key_file"`
+	ServerName string `toml:"server_name"`
+	MinVersion string `toml:"min_version"` // "1.0", "1.1", "1.2" or "1.3"
+}
+
+// ManagerRetryCfg represents retries of transient manager check failures
+type ManagerRetryCfg struct {
+	MaxAttempts     int `toml:"max_attempts"` // total attempts including the first one
+	BaseDelayMillis int `toml:"base_delay_ms"`
+	MaxDelayMillis  int `toml:"max_delay_ms"`
+}
+
+// ManagerStatusPolicyCfg represents how the overall /check-manager status is derived
+type ManagerStatusPolicyCfg struct {
+	Mode               string `toml:"mode"`                  // "any_down" (default), "quorum" or "weighted"
+	Quorum             int    `toml:"quorum"`                // quorum: managers that must be up, majority when 0
+	MinUpWeightPercent int    `toml:"min_up_weight_percent"` // weighted: share of the total weight that must be up, 50 when absent
+}
+
+// ManagerCfg represents manager service configuration.
+// Managers are listed as structured targets; urls is the shorthand for
+// targets that only need a base URL and an optional name ("name=url").
 type ManagerCfg struct {
-	URLs           []string `toml:"urls"`
-	TimeoutSeconds int      `toml:"timeout_seconds"`
+	URLs           []string               `toml:"urls"`
+	Targets        []ManagerTargetCfg     `toml:"targets"`
+	TimeoutSeconds int                    `toml:"timeout_seconds"`
+	Concurrency    int                    `toml:"concurrency"`
+	TLS            ManagerTLSCfg          `toml:"tls"`
+	Retry          ManagerRetryCfg        `toml:"retry"`
+	StatusPolicy   ManagerStatusPolicyCfg `toml:"status_policy"`
+}
+
+// SchedulerCfg represents background manager check scheduling configuration
+type SchedulerCfg struct {
+	Enabled         bool `toml:"enabled"`
+	IntervalSeconds int  `toml:"interval_seconds"`
+	JitterSeconds   int  `toml:"jitter_seconds"`
+}
+
+// HealthCfg represents the liveness and readiness checks of the agent itself
+type HealthCfg struct {
+	CheckTimeoutMillis     int    `toml:"check_timeout_ms"`
+	SchedulerMaxAgeSeconds int    `toml:"scheduler_max_age_seconds"` // defaults to three scheduler intervals
+	DiskPath               string `toml:"disk_path"`
+	MinFreeDiskMB          int    `toml:"min_free_disk_mb"`
+}
+
+// RetentionCfg represents downsampling and purging of raw health_calls and manager_checks
+type RetentionCfg struct {
+	Enabled              bool `toml:"enabled"`
+	IntervalSeconds      int  `toml:"interval_seconds"`
+	BatchSize            int  `toml:"batch_size"`              // rows deleted per statement
+	HealthCallsTTLDays   int  `toml:"health_calls_ttl_days"`   // 0 keeps raw rows forever
+	ManagerChecksTTLDays int  `toml:"manager_checks_ttl_days"` // 0 keeps raw rows forever
+	RollupLagSeconds     int  `toml:"rollup_lag_seconds"`      // buckets are rolled up this long after they end
+
+	// Monthly partitions of manager_checks are maintained even when retention is disabled
+	PartitionMonthsAhead    int  `toml:"partition_months_ahead"`    // partitions created after the current month
+	DetachExpiredPartitions bool `toml:"detach_expired_partitions"` // detach instead of dropping expired partitions
+}
+
+// WriterCfg represents buffered background writing of manager checks
+type WriterCfg struct {
+	BufferSize           int `toml:"buffer_size"`        // checks buffered before callers are held back
+	BatchSize            int `toml:"batch_size"`         // checks written per INSERT at most
+	FlushIntervalMillis  int `toml:"flush_interval_ms"`  // buffered checks are written at least this often
+	EnqueueTimeoutMillis int `toml:"enqueue_timeout_ms"` // how long a caller waits for room in a full buffer
+
+	// Checks that fail to be written are spooled to a local file and replayed; empty path disables it
+	OutboxPath                  string `toml:"outbox_path"`
+	OutboxReplayIntervalSeconds int    `toml:"outbox_replay_interval_seconds"`
+}
+
+// HealthCallsCfg represents sampling of recorded /health calls
+type HealthCallsCfg struct {
+	SampleEvery       int `toml:"sample_every"`    // record one call in N, 1 records every call
+	MinIntervalMillis int `toml:"min_interval_ms"` // record at most one call per interval, 0 means no limit
+	BufferSize        int `toml:"buffer_size"`     // calls waiting to be written before new ones are dropped
+}
+
+// MigratorCfg represents migrator configuration
+type MigratorCfg struct {
+	LockTimeoutSeconds int `toml:"lock_timeout_seconds"`
+}
+
+// AlertWebhookCfg represents a generic JSON webhook alert sink
+type AlertWebhookCfg struct {
+	URL string `toml:"url"`
+}
+
+// AlertSlackCfg represents a Slack-compatible incoming webhook alert sink
+type AlertSlackCfg struct {
+	WebhookURL string `toml:"webhook_url"`
+}
+
+// AlertEmailCfg represents an SMTP alert sink.
+// The SMTP password is read from ALERT_SMTP_PASSWORD only.
+type AlertEmailCfg struct {
+	SMTPHost     string   `toml:"smtp_host"`
+	SMTPPort     int      `toml:"smtp_port"`
+	SMTPUsername string   `toml:"smtp_username"`
+	From         string   `toml:"from"`
+	To           []string `toml:"to"`
+}
+
+// AlertingCfg represents manager state-transition alerting configuration
+type AlertingCfg struct {
+	Enabled                 bool            `toml:"enabled"`
+	FailureThreshold        int             `toml:"failure_threshold"`  // consecutive failures to mark a manager down
+	RecoveryThreshold       int             `toml:"recovery_threshold"` // consecutive successes to mark it up again
+	RenotifyIntervalSeconds int             `toml:"renotify_interval_seconds"`
+	DedupIntervalSeconds    int             `toml:"dedup_interval_seconds"`
+	Webhook                 AlertWebhookCfg `toml:"webhook"`
+	Slack                   AlertSlackCfg   `toml:"slack"`
+	Email                   AlertEmailCfg   `toml:"email"`
 }
 
 // Config represents the application configuration
 type Config struct {
-	ServiceName string      `toml:"service_name"`
-	ServiceEnv  string      `toml:"service_env"`
-	HTTPPort    int         `toml:"http_port"`
-	Database    DatabaseCfg `toml:"database"`
-	TLS         TLSConfig   `toml:"tls"`
-	Manager     ManagerCfg  `toml:"manager"`
+	ServiceName            string         `toml:"service_name"`
+	ServiceEnv             string         `toml:"service_env"`
+	HTTPPort               int            `toml:"http_port"`
+	ShutdownTimeoutSeconds int            `toml:"shutdown_timeout_seconds"`
+	Database               DatabaseCfg    `toml:"database"`
+	TLS                    TLSConfig      `toml:"tls"`
+	Manager                ManagerCfg     `toml:"manager"`
+	Scheduler              SchedulerCfg   `toml:"scheduler"`
+	Migrator               MigratorCfg    `toml:"migrator"`
+	Alerting               AlertingCfg    `toml:"alerting"`
+	Health                 HealthCfg      `toml:"health"`
+	Retention              RetentionCfg   `toml:"retention"`
+	Writer                 WriterCfg      `toml:"writer"`
+	HealthCalls            HealthCallsCfg `toml:"health_calls"`
+
+	path    string
+	sources map[string]ValueSource
+	targets []ManagerTargetCfg
 }
 
-func Load() (*Config, error) {
+// Load reads configuration from a TOML file and applies environment overrides on top.
+// The file path is taken from the path argument, then from CONFIG_PATH, and finally
+// falls back to DefaultConfigPath. Only an explicitly requested file must exist.
+func Load(path string) (*Config, error) {
 	// Load .env file if in local environment
 	_ = godotenv.Load(".env")
 
-	var cfg Config
+	cfg := Config{sources: make(map[string]ValueSource)}
+
+	required := true
+	if path == "" {
+		path = os.Getenv("CONFIG_PATH")
+	}
+	if path == "" {
+		path = DefaultConfigPath
+		required = false
+	}
+
+	if err := cfg.loadFile(path, required); err != nil {
+		return nil, err
+	}
+
+	l := &envLoader{cfg: &cfg}
 
 	// Override with environment variables
+	// Service configuration
+	l.setString("SERVICE_NAME", "service_name", &cfg.ServiceName)
+	l.setString("SERVICE_ENV", "service_env", &cfg.ServiceEnv)
+
 	// Database configuration
-	if host := os.Getenv("DB_HOST"); host != "" {
-		cfg.Database.Host = host
+	l.setString("DB_HOST", "database.host", &cfg.Database.Host)
+	l.setInt("DB_PORT", "database.port", &cfg.Database.Port)
+	l.setString("DB_USER", "database.user", &cfg.Database.User)
+	l.setString("DB_NAME", "database.name", &cfg.Database.Name)
+	l.setString("DB_SSLMODE", "database.sslmode", &cfg.Database.SSLMode)
+
+	// App configuration
+	l.setInt("APP_PORT", "http_port", &cfg.HTTPPort)
+	l.setInt("SHUTDOWN_TIMEOUT", "shutdown_timeout_seconds", &cfg.ShutdownTimeoutSeconds)
+
+	// TLS configuration
+	l.setBool("TLS_ENABLED", "tls.enabled", &cfg.TLS.Enabled)
+	l.setString("TLS_CERT_FILE", "tls.cert_file", &cfg.TLS.CertFile)
+	l.setString("TLS_KEY_FILE", "tls.key_file", &cfg.TLS.KeyFile)
+	l.setString("TLS_CA_FILE", "tls.ca_file", &cfg.TLS.CAFile)
+
+	// Manager configuration
+	l.setStrings("MANAGER_URLS", "manager.urls", &cfg.Manager.URLs, ParseManagerURLs)
+	l.setInt("MANAGER_TIMEOUT", "manager.timeout_seconds", &cfg.Manager.TimeoutSeconds)
+	l.setInt("MANAGER_CONCURRENCY", "manager.concurrency", &cfg.Manager.Concurrency)
+	l.setString("MANAGER_TLS_CA_FILE", "manager.tls.ca_file", &cfg.Manager.TLS.CAFile)
+	l.setString("MANAGER_TLS_CERT_FILE", "manager.tls.cert_file", &cfg.Manager.TLS.CertFile)
+	l.setString("MANAGER_TLS_KEY_FILE", "manager.tls.key_file", &cfg.Manager.TLS.KeyFile)
+	l.setString("MANAGER_TLS_SERVER_NAME", "manager.tls.server_name", &cfg.Manager.TLS.ServerName)
+	l.setString("MANAGER_TLS_MIN_VERSION", "manager.tls.min_version", &cfg.Manager.TLS.MinVersion)
+	l.setInt("MANAGER_RETRY_MAX_ATTEMPTS", "manager.retry.max_attempts", &cfg.Manager.Retry.MaxAttempts)
+	l.setInt("MANAGER_RETRY_BASE_DELAY_MS", "manager.retry.base_delay_ms", &cfg.Manager.Retry.BaseDelayMillis)
+	l.setInt("MANAGER_RETRY_MAX_DELAY_MS", "manager.retry.max_delay_ms", &cfg.Manager.Retry.MaxDelayMillis)
+	l.setString("MANAGER_STATUS_POLICY", "manager.status_policy.mode", &cfg.Manager.StatusPolicy.Mode)
+	l.setInt("MANAGER_STATUS_QUORUM", "manager.status_policy.quorum", &cfg.Manager.StatusPolicy.Quorum)
+	l.setInt("MANAGER_STATUS_MIN_UP_WEIGHT_PERCENT", "manager.status_policy.min_up_weight_percent",
+		&cfg.Manager.StatusPolicy.MinUpWeightPercent)
+
+	// Scheduler configuration
+	l.setBool("SCHEDULER_ENABLED", "scheduler.enabled", &cfg.Scheduler.Enabled)
+	l.setInt("SCHEDULER_INTERVAL", "scheduler.interval_seconds", &cfg.Scheduler.IntervalSeconds)
+	l.setInt("SCHEDULER_JITTER", "scheduler.jitter_seconds", &cfg.Scheduler.JitterSeconds)
+
+	// Migrator configuration
+	l.setInt("MIGRATOR_LOCK_TIMEOUT", "migrator.lock_timeout_seconds", &cfg.Migrator.LockTimeoutSeconds)
+
+	// Health check configuration
+	l.setInt("HEALTH_CHECK_TIMEOUT_MS", "health.check_timeout_ms", &cfg.Health.CheckTimeoutMillis)
+	l.setInt("HEALTH_SCHEDULER_MAX_AGE", "health.scheduler_max_age_seconds", &cfg.Health.SchedulerMaxAgeSeconds)
+	l.setString("HEALTH_DISK_PATH", "health.disk_path", &cfg.Health.DiskPath)
+	l.setInt("HEALTH_MIN_FREE_DISK_MB", "health.min_free_disk_mb", &cfg.Health.MinFreeDiskMB)
+
+	// Retention configuration
+	l.setBool("RETENTION_ENABLED", "retention.enabled", &cfg.Retention.Enabled)
+	l.setInt("RETENTION_INTERVAL", "retention.interval_seconds", &cfg.Retention.IntervalSeconds)
+	l.setInt("RETENTION_BATCH_SIZE", "retention.batch_size", &cfg.Retention.BatchSize)
+	l.setInt("RETENTION_HEALTH_CALLS_TTL_DAYS", "retention.health_calls_ttl_days", &cfg.Retention.HealthCallsTTLDays)
+	l.setInt("RETENTION_MANAGER_CHECKS_TTL_DAYS", "retention.manager_checks_ttl_days", &cfg.Retention.ManagerChecksTTLDays)
+	l.setInt("RETENTION_ROLLUP_LAG", "retention.rollup_lag_seconds", &cfg.Retention.RollupLagSeconds)
+	l.setInt("RETENTION_PARTITION_MONTHS_AHEAD", "retention.partition_months_ahead", &cfg.Retention.PartitionMonthsAhead)
+	l.setBool("RETENTION_DETACH_EXPIRED_PARTITIONS", "retention.detach_expired_partitions", &cfg.Retention.DetachExpiredPartitions)
+
+	// Health call recording configuration
+	l.setInt("HEALTH_CALLS_SAMPLE_EVERY", "health_calls.sample_every", &cfg.HealthCalls.SampleEvery)
+	l.setInt("HEALTH_CALLS_MIN_INTERVAL_MS", "health_calls.min_interval_ms", &cfg.HealthCalls.MinIntervalMillis)
+	l.setInt("HEALTH_CALLS_BUFFER_SIZE", "health_calls.buffer_size", &cfg.HealthCalls.BufferSize)
+
+	// Manager check writer configuration
+	l.setInt("WRITER_BUFFER_SIZE", "writer.buffer_size", &cfg.Writer.BufferSize)
+	l.setInt("WRITER_BATCH_SIZE", "writer.batch_size", &cfg.Writer.BatchSize)
+	l.setInt("WRITER_FLUSH_INTERVAL_MS", "writer.flush_interval_ms", &cfg.Writer.FlushIntervalMillis)
+	l.setInt("WRITER_ENQUEUE_TIMEOUT_MS", "writer.enqueue_timeout_ms", &cfg.Writer.EnqueueTimeoutMillis)
+	l.setString("WRITER_OUTBOX_PATH", "writer.outbox_path", &cfg.Writer.OutboxPath)
+	l.setInt("WRITER_OUTBOX_REPLAY_INTERVAL", "writer.outbox_replay_interval_seconds", &cfg.Writer.OutboxReplayIntervalSeconds)
+
+	// Alerting configuration
+	l.setBool("ALERTING_ENABLED", "alerting.enabled", &cfg.Alerting.Enabled)
+	l.setInt("ALERT_FAILURE_THRESHOLD", "alerting.failure_threshold", &cfg.Alerting.FailureThreshold)
+	l.setInt("ALERT_RECOVERY_THRESHOLD", "alerting.recovery_threshold", &cfg.Alerting.RecoveryThreshold)
+	l.setInt("ALERT_RENOTIFY_INTERVAL", "alerting.renotify_interval_seconds", &cfg.Alerting.RenotifyIntervalSeconds)
+	l.setInt("ALERT_DEDUP_INTERVAL", "alerting.dedup_interval_seconds", &cfg.Alerting.DedupIntervalSeconds)
+	l.setString("ALERT_WEBHOOK_URL", "alerting.webhook.url", &cfg.Alerting.Webhook.URL)
+	l.setString("ALERT_SLACK_WEBHOOK_URL", "alerting.slack.webhook_url", &cfg.Alerting.Slack.WebhookURL)
+	l.setString("ALERT_SMTP_HOST", "alerting.email.smtp_host", &cfg.Alerting.Email.SMTPHost)
+	l.setInt("ALERT_SMTP_PORT", "alerting.email.smtp_port", &cfg.Alerting.Email.SMTPPort)
+	l.setString("ALERT_SMTP_USERNAME", "alerting.email.smtp_username", &cfg.Alerting.Email.SMTPUsername)
+	l.setString("ALERT_EMAIL_FROM", "alerting.email.from", &cfg.Alerting.Email.From)
+	l.setStrings("ALERT_EMAIL_TO", "alerting.email.to", &cfg.Alerting.Email.To, parseList)
+
+	if l.err != nil {
+		return nil, l.err
+	}
+
+	// Set defaults
+	if cfg.Database.SSLMode == "" {
+		cfg.Database.SSLMode = "disable"
+	}
+	if cfg.ShutdownTimeoutSeconds == 0 {
+		cfg.ShutdownTimeoutSeconds = 15
+	}
+	if cfg.Manager.TimeoutSeconds == 0 {
+		cfg.Manager.TimeoutSeconds = 5
+	}
+	if cfg.Manager.Concurrency == 0 {
+		cfg.Manager.Concurrency = 4
+	}
+	if cfg.Manager.TLS.MinVersion == "" {
+		cfg.Manager.TLS.MinVersion = "1.2"
+	}
+	if cfg.Manager.Retry.MaxAttempts == 0 {
+		cfg.Manager.Retry.MaxAttempts = 3
+	}
+	if cfg.Manager.Retry.BaseDelayMillis == 0 {
+		cfg.Manager.Retry.BaseDelayMillis = 200
+	}
+	if cfg.Manager.Retry.MaxDelayMillis == 0 {
+		cfg.Manager.Retry.MaxDelayMillis = 2000
+	}
+
+	cfg.Manager.StatusPolicy.Mode = strings.ToLower(cfg.Manager.StatusPolicy.Mode)
+	switch cfg.Manager.StatusPolicy.Mode {
+	case "":
+		cfg.Manager.StatusPolicy.Mode = "any_down"
+	case "any_down", "quorum", "weighted":
+	default:
+		return nil, fmt.Errorf("unknown manager status policy %q", cfg.Manager.StatusPolicy.Mode)
+	}
+	// 0 is a valid share, so the default only applies when the key is absent
+	if !cfg.isSet("manager.status_policy.min_up_weight_percent") {
+		cfg.Manager.StatusPolicy.MinUpWeightPercent = 50
+	}
+	if p := cfg.Manager.StatusPolicy.MinUpWeightPercent; p < 0 || p > 100 {
+		return nil, fmt.Errorf("manager status policy: min_up_weight_percent must be within 0..100, got %d", p)
 	}
-	if port := os.Getenv("DB_PORT"); port != "" {
-		parsedPort, err := parseIntEnv("DB_PORT", port)
-		if err != nil {
-			return nil, err
+
+	targets, err := resolveManagerTargets(cfg.Manager)
+	if err != nil {
+		return nil, err
+	}
+	cfg.targets = targets
+
+	if cfg.Manager.StatusPolicy.Mode == "quorum" {
+		enabled := len(cfg.GetManagerURLs())
+		if q := cfg.Manager.StatusPolicy.Quorum; q < 0 || q > enabled {
+			return nil, fmt.Errorf("manager status policy: quorum must be within 0..%d (enabled managers), got %d", enabled, q)
 		}
-		cfg.Database.Port = parsedPort
 	}
-	if user := os.Getenv("DB_USER"); user != "" {
-		cfg.Database.User = user
+	if cfg.Scheduler.IntervalSeconds == 0 {
+		cfg.Scheduler.IntervalSeconds = 30
+	}
+	if cfg.Migrator.LockTimeoutSeconds == 0 {
+		cfg.Migrator.LockTimeoutSeconds = 60
+	}
+	if cfg.Alerting.FailureThreshold == 0 {
+		cfg.Alerting.FailureThreshold = 3
+	}
+	if cfg.Alerting.RecoveryThreshold == 0 {
+		cfg.Alerting.RecoveryThreshold = 2
+	}
+	if cfg.Alerting.DedupIntervalSeconds == 0 {
+		cfg.Alerting.DedupIntervalSeconds = 300
+	}
+	if cfg.Alerting.Email.SMTPPort == 0 {
+		cfg.Alerting.Email.SMTPPort = 587
+	}
+	if cfg.Retention.IntervalSeconds == 0 {
+		cfg.Retention.IntervalSeconds = 3600
+	}
+	if cfg.Retention.BatchSize == 0 {
+		cfg.Retention.BatchSize = 5000
+	}
+	if cfg.Retention.RollupLagSeconds == 0 {
+		cfg.Retention.RollupLagSeconds = 3600
+	}
+	if cfg.Retention.PartitionMonthsAhead == 0 {
+		cfg.Retention.PartitionMonthsAhead = 2
+	}
+	if cfg.HealthCalls.SampleEvery == 0 {
+		cfg.HealthCalls.SampleEvery = 1
+	}
+	if cfg.HealthCalls.BufferSize == 0 {
+		cfg.HealthCalls.BufferSize = 1000
+	}
+	if cfg.Writer.BufferSize == 0 {
+		cfg.Writer.BufferSize = 1000
+	}
+	if cfg.Writer.BatchSize == 0 {
+		cfg.Writer.BatchSize = 100
+	}
+	if cfg.Writer.FlushIntervalMillis == 0 {
+		cfg.Writer.FlushIntervalMillis = 1000
+	}
+	if cfg.Writer.EnqueueTimeoutMillis == 0 {
+		cfg.Writer.EnqueueTimeoutMillis = 1000
 	}
-	if name := os.Getenv("DB_NAME"); name != "" {
-		cfg.Database.Name = name
+	if cfg.Writer.OutboxReplayIntervalSeconds == 0 {
+		cfg.Writer.OutboxReplayIntervalSeconds = 30
 	}
-	if sslmode := os.Getenv("DB_SSLMODE"); sslmode != "" {
-		cfg.Database.SSLMode = sslmode
+	if cfg.Health.CheckTimeoutMillis == 0 {
+		cfg.Health.CheckTimeoutMillis = 2000
+	}
+	if cfg.Health.SchedulerMaxAgeSeconds == 0 {
+		cfg.Health.SchedulerMaxAgeSeconds = 3 * cfg.Scheduler.IntervalSeconds
+	}
+	if cfg.Health.DiskPath == "" {
+		cfg.Health.DiskPath = "/"
+	}
+	if cfg.Health.MinFreeDiskMB == 0 {
+		cfg.Health.MinFreeDiskMB = 100
 	}
 
-	// App configuration
-	if port := os.Getenv("APP_PORT"); port != "" {
-		parsedPort, err := parseIntEnv("APP_PORT", port)
-		if err != nil {
-			return nil, err
+	return &cfg, nil
+}
+
+// loadFile decodes the TOML file into the config and records which keys it defined
+func (c *Config) loadFile(path string, required bool) error {
+	meta, err := toml.DecodeFile(path, c)
+	if err != nil {
+		if !required && errors.Is(err, fs.ErrNotExist) {
+			return nil
 		}
-		cfg.HTTPPort = parsedPort
+		return fmt.Errorf("failed to read config file %s: %w", path, err)
 	}
+	c.path = path
 
-	// TLS configuration
-	if tlsEnabled := os.Getenv("TLS_ENABLED"); tlsEnabled != "" {
-		cfg.TLS.Enabled = strings.ToLower(tlsEnabled) == "true"
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, 0, len(undecoded))
+		for _, key := range undecoded {
+			keys = append(keys, key.String())
+		}
+		return fmt.Errorf("unknown keys in config file %s: %s", path, strings.Join(keys, ", "))
 	}
-	if certFile := os.Getenv("TLS_CERT_FILE"); certFile != "" {
-		cfg.TLS.CertFile = certFile
+
+	for _, key := range meta.Keys() {
+		c.sources[key.String()] = ValueSource{Key: key.String(), Source: SourceFile, Origin: path}
 	}
-	if keyFile := os.Getenv("TLS_KEY_FILE"); keyFile != "" {
-		cfg.TLS.KeyFile = keyFile
+	return nil
+}
+
+// Path returns the config file that was loaded, or an empty string if none was
+func (c *Config) Path() string {
+	return c.path
+}
+
+// isSet reports whether the key was given in the file or the environment
+func (c *Config) isSet(key string) bool {
+	_, ok := c.sources[key]
+	return ok
+}
+
+// Sources reports where every configuration value came from, sorted by key
+func (c *Config) Sources() []ValueSource {
+	keys := leafKeys(reflect.TypeOf(*c), "")
+	sort.Strings(keys)
+
+	sources := make([]ValueSource, 0, len(keys))
+	for _, key := range keys {
+		if src, ok := c.sources[key]; ok {
+			sources = append(sources, src)
+			continue
+		}
+		sources = append(sources, ValueSource{Key: key, Source: SourceDefault})
 	}
-	if caFile := os.Getenv("TLS_CA_FILE"); caFile != "" {
-		cfg.TLS.CAFile = caFile
+	return sources
+}
+
+// leafKeys lists dotted TOML keys of all non-table fields of a struct type
+func leafKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("toml")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		key := prefix + strings.Split(tag, ",")[0]
+		if field.Type.Kind() == reflect.Struct {
+			keys = append(keys, leafKeys(field.Type, key+".")...)
+			continue
+		}
+		keys = append(keys, key)
 	}
+	return keys
+}
 
-	// Manager configuration
-	if managerURLs := os.Getenv("MANAGER_URLS"); managerURLs != "" {
-		cfg.Manager.URLs = ParseManagerURLs(managerURLs)
+// envLoader applies environment overrides and keeps the first parse error
+type envLoader struct {
+	cfg *Config
+	err error
+}
+
+func (l *envLoader) lookup(env, key string) (string, bool) {
+	value := os.Getenv(env)
+	if value == "" {
+		return "", false
 	}
-	if timeout := os.Getenv("MANAGER_TIMEOUT"); timeout != "" {
-		parsedTimeout, err := parseIntEnv("MANAGER_TIMEOUT", timeout)
-		if err != nil {
-			return nil, err
-		}
-		cfg.Manager.TimeoutSeconds = parsedTimeout
+	l.cfg.sources[key] = ValueSource{Key: key, Source: SourceEnv, Origin: env}
+	return value, true
+}
+
+func (l *envLoader) setString(env, key string, dst *string) {
+	if value, ok := l.lookup(env, key); ok {
+		*dst = value
 	}
+}
 
-	// Set defaults
-	if cfg.Database.SSLMode == "" {
-		cfg.Database.SSLMode = "disable"
+func (l *envLoader) setInt(env, key string, dst *int) {
+	value, ok := l.lookup(env, key)
+	if !ok || l.err != nil {
+		return
 	}
-	if cfg.Manager.TimeoutSeconds == 0 {
-		cfg.Manager.TimeoutSeconds = 5
+	parsed, err := parseIntEnv(env, value)
+	if err != nil {
+		l.err = err
+		return
 	}
+	*dst = parsed
+}
 
-	return &cfg, nil
+func (l *envLoader) setBool(env, key string, dst *bool) {
+	if value, ok := l.lookup(env, key); ok {
+		*dst = strings.ToLower(value) == "true"
+	}
+}
+
+func (l *envLoader) setStrings(env, key string, dst *[]string, parse func(string) []string) {
+	if value, ok := l.lookup(env, key); ok {
+		*dst = parse(value)
+	}
 }
 
 // ParseManagerURLs parses comma-separated manager URLs from environment variable
 func ParseManagerURLs(urlsStr string) []string {
-	if urlsStr == "" {
+	return parseList(urlsStr)
+}
+
+// parseList splits a comma-separated list, dropping empty items
+func parseList(value string) []string {
+	if value == "" {
 		return nil
 	}
 
-	var urls []string
-	for _, url := range strings.Split(urlsStr, ",") {
-		url = strings.TrimSpace(url)
-		if url != "" {
-			urls = append(urls, url)
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
 		}
 	}
-	return urls
+	return items
 }
 
 // GetDSN returns PostgreSQL DSN string
@@ -150,9 +591,25 @@ func (c *Config) GetDSN() string {
 	)
 }
 
-// GetManagerURLs returns the list of manager service URLs
+// GetSMTPPassword returns the alert SMTP password, which is only read from the environment
+func (c *Config) GetSMTPPassword() string {
+	return os.Getenv("ALERT_SMTP_PASSWORD")
+}
+
+// GetManagerTargets returns all configured managers with defaults applied, including disabled ones
+func (c *Config) GetManagerTargets() []ManagerTargetCfg {
+	return c.targets
+}
+
+// GetManagerURLs returns the base URLs of enabled managers
 func (c *Config) GetManagerURLs() []string {
-	return c.Manager.URLs
+	var urls []string
+	for _, target := range c.targets {
+		if target.IsEnabled() {
+			urls = append(urls, target.URL)
+		}
+	}
+	return urls
 }
 
 // GetManagerTimeout returns the manager timeout in seconds
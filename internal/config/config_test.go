@@ -0,0 +1,382 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "app.toml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	return path
+}
+
+func sourceOf(t *testing.T, cfg *Config, key string) ValueSource {
+	t.Helper()
+
+	for _, src := range cfg.Sources() {
+		if src.Key == key {
+			return src
+		}
+	}
+	t.Fatalf("key %s not reported in sources", key)
+	return ValueSource{}
+}
+
+func TestLoad_FileWithEnvOverrides(t *testing.T) {
+	path := writeConfig(t, `
+service_name = "agent"
+service_env = "prod"
+http_port = 8080
+
+[database]
+host = "db.internal"
+port = 5432
+
+[manager]
+urls = ["http://m1:8081", "http://m2:8081"]
+`)
+	t.Setenv("DB_HOST", "db.override")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.ServiceName != "agent" || cfg.ServiceEnv != "prod" {
+		t.Fatalf("Expected service agent/prod, got %s/%s", cfg.ServiceName, cfg.ServiceEnv)
+	}
+	if cfg.Database.Host != "db.override" {
+		t.Fatalf("Expected env override for DB host, got %s", cfg.Database.Host)
+	}
+	if cfg.Database.Port != 5432 {
+		t.Fatalf("Expected port from file, got %d", cfg.Database.Port)
+	}
+	if len(cfg.Manager.URLs) != 2 {
+		t.Fatalf("Expected 2 manager URLs, got %d", len(cfg.Manager.URLs))
+	}
+	if cfg.Manager.TimeoutSeconds != 5 {
+		t.Fatalf("Expected default timeout 5, got %d", cfg.Manager.TimeoutSeconds)
+	}
+
+	if src := sourceOf(t, cfg, "database.host"); src.Source != SourceEnv || src.Origin != "DB_HOST" {
+		t.Fatalf("Expected database.host from env DB_HOST, got %+v", src)
+	}
+	if src := sourceOf(t, cfg, "database.port"); src.Source != SourceFile || src.Origin != path {
+		t.Fatalf("Expected database.port from file, got %+v", src)
+	}
+	if src := sourceOf(t, cfg, "manager.timeout_seconds"); src.Source != SourceDefault {
+		t.Fatalf("Expected manager.timeout_seconds default, got %+v", src)
+	}
+}
+
+func TestLoad_ConfigPathEnv(t *testing.T) {
+	path := writeConfig(t, `service_name = "from-env-path"`)
+	t.Setenv("CONFIG_PATH", path)
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.ServiceName != "from-env-path" {
+		t.Fatalf("Expected service name from CONFIG_PATH file, got %s", cfg.ServiceName)
+	}
+	if cfg.Path() != path {
+		t.Fatalf("Expected path %s, got %s", path, cfg.Path())
+	}
+}
+
+func TestLoad_MissingExplicitFile(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.toml")); err == nil {
+		t.Fatal("Expected error for missing explicit config file")
+	}
+}
+
+func TestLoad_UnknownKey(t *testing.T) {
+	path := writeConfig(t, `
+[database]
+hots = "typo"
+`)
+
+	if _, err := Load(path); err == nil {
+		t.Fatal("Expected error for unknown key")
+	}
+}
+
+func TestLoad_ManagerTargets(t *testing.T) {
+	path := writeConfig(t, `
+[manager]
+urls = ["legacy=http://m3:8081"]
+timeout_seconds = 7
+
+[[manager.targets]]
+name = "eu"
+url = "https://eu.manager:8443/"
+health_path = "api/v1/health"
+method = "head"
+headers = { Authorization = "Bearer token" }
+expected_status = [200, 204]
+expected_field = "-"
+tags = ["eu", "prod"]
+
+[[manager.targets]]
+url = "http://m2:8081"
+timeout_seconds = 2
+enabled = false
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	targets := cfg.GetManagerTargets()
+	if len(targets) != 3 {
+		t.Fatalf("Expected 3 targets, got %d", len(targets))
+	}
+
+	eu := targets[0]
+	if eu.URL != "https://eu.manager:8443" || eu.HealthPath != "/api/v1/health" || eu.Method != "HEAD" {
+		t.Fatalf("Unexpected eu target: %+v", eu)
+	}
+	if eu.TimeoutSeconds != 7 || eu.ExpectedField != "" || len(eu.ExpectedStatus) != 2 {
+		t.Fatalf("Unexpected eu target defaults: %+v", eu)
+	}
+	if eu.Headers["Authorization"] != "Bearer token" {
+		t.Fatalf("Expected headers from file, got %v", eu.Headers)
+	}
+
+	if targets[1].Name != "http://m2:8081" || targets[1].IsEnabled() || targets[1].TimeoutSeconds != 2 {
+		t.Fatalf("Unexpected disabled target: %+v", targets[1])
+	}
+
+	legacy := targets[2]
+	if legacy.Name != "legacy" || legacy.URL != "http://m3:8081" || legacy.HealthPath != "/health" {
+		t.Fatalf("Unexpected legacy target: %+v", legacy)
+	}
+	if legacy.ExpectedField != "status" || legacy.ExpectedValue != "success" {
+		t.Fatalf("Expected default body check for legacy target, got %+v", legacy)
+	}
+
+	urls := cfg.GetManagerURLs()
+	if len(urls) != 2 || urls[0] != "https://eu.manager:8443" || urls[1] != "http://m3:8081" {
+		t.Fatalf("Expected enabled manager URLs, got %v", urls)
+	}
+}
+
+func TestLoad_DuplicateManagerName(t *testing.T) {
+	path := writeConfig(t, `service_name = "agent"`)
+	t.Setenv("MANAGER_URLS", "a=http://m1:8081,a=http://m2:8081")
+
+	if _, err := Load(path); err == nil {
+		t.Fatal("Expected error for duplicate manager name")
+	}
+}
+
+func TestParseManagerEntry(t *testing.T) {
+	tests := []struct {
+		entry string
+		name  string
+		url   string
+	}{
+		{"http://m1:8081", "", "http://m1:8081"},
+		{"eu=https://m1:8443", "eu", "https://m1:8443"},
+		{" eu = https://m1:8443 ", "eu", "https://m1:8443"},
+		{"http://m1:8081/?token=abc", "", "http://m1:8081/?token=abc"},
+	}
+	for _, tt := range tests {
+		name, url := ParseManagerEntry(tt.entry)
+		if name != tt.name || url != tt.url {
+			t.Errorf("ParseManagerEntry(%q) = %q, %q; want %q, %q", tt.entry, name, url, tt.name, tt.url)
+		}
+	}
+}
+
+func TestLoad_ManagerTargetProbeTypes(t *testing.T) {
+	path := writeConfig(t, `
+[[manager.targets]]
+url = "tcp://db:5432"
+
+[[manager.targets]]
+url = "grpcs://manager:8443"
+grpc_service = "manager.v1.Manager"
+
+[[manager.targets]]
+type = "tls"
+url = "https://manager:8443"
+cert_min_valid_days = 14
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	targets := cfg.GetManagerTargets()
+	expected := []string{"tcp", "grpc", "tls"}
+	for i, target := range targets {
+		if target.Type != expected[i] {
+			t.Errorf("Expected target %s to use %s probe, got %s", target.URL, expected[i], target.Type)
+		}
+		if target.HealthPath != "" || target.ExpectedField != "" {
+			t.Errorf("Expected no HTTP defaults for %s target, got %+v", target.Type, target)
+		}
+	}
+}
+
+func TestLoad_UnknownProbeType(t *testing.T) {
+	path := writeConfig(t, `
+[[manager.targets]]
+url = "ftp://files:21"
+`)
+
+	if _, err := Load(path); err == nil {
+		t.Fatal("Expected error for a URL scheme without a probe type")
+	}
+}
+
+func TestLoad_ManagerTargetAssertions(t *testing.T) {
+	path := writeConfig(t, `
+[[manager.targets]]
+url = "http://m1:8081"
+max_body_bytes = 4096
+max_latency_ms = 500
+
+[[manager.targets.assertions]]
+path = "components.*"
+value = "ok"
+
+[[manager.targets.assertions]]
+source = "Header"
+path = "Content-Type"
+op = "Contains"
+value = "json"
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	target := cfg.GetManagerTargets()[0]
+	if target.MaxBodyBytes != 4096 || target.MaxLatencyMillis != 500 || len(target.Assertions) != 2 {
+		t.Fatalf("Unexpected target: %+v", target)
+	}
+	if a := target.Assertions[0]; a.Source != "body" || a.Op != "equals" {
+		t.Fatalf("Expected body equals defaults, got %+v", a)
+	}
+	if a := target.Assertions[1]; a.Source != "header" || a.Op != "contains" {
+		t.Fatalf("Expected normalised header assertion, got %+v", a)
+	}
+}
+
+func TestLoad_InvalidAssertion(t *testing.T) {
+	tests := map[string]string{
+		"unknown operator": `op = "greater"`,
+		"unknown source":   `source = "cookie"`,
+		"invalid pattern": `op = "matches"
+value = "(["`,
+	}
+	for name, assertion := range tests {
+		path := writeConfig(t, `
+[[manager.targets]]
+url = "http://m1:8081"
+
+[[manager.targets.assertions]]
+path = "status"
+`+assertion)
+
+		if _, err := Load(path); err == nil {
+			t.Errorf("Expected error for %s", name)
+		}
+	}
+}
+
+func TestLoad_StatusPolicy(t *testing.T) {
+	path := writeConfig(t, `
+[manager.status_policy]
+mode = "Weighted"
+
+[[manager.targets]]
+url = "http://m1:8081"
+weight = 3
+degraded_latency_ms = 300
+
+[[manager.targets.assertions]]
+path = "components.cache"
+value = "ok"
+severity = "degraded"
+`)
+	t.Setenv("MANAGER_STATUS_MIN_UP_WEIGHT_PERCENT", "75")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	policy := cfg.Manager.StatusPolicy
+	if policy.Mode != "weighted" || policy.MinUpWeightPercent != 75 {
+		t.Fatalf("Unexpected status policy: %+v", policy)
+	}
+
+	target := cfg.GetManagerTargets()[0]
+	if target.Weight != 3 || target.DegradedLatencyMillis != 300 || target.Assertions[0].Severity != "degraded" {
+		t.Fatalf("Unexpected target: %+v", target)
+	}
+}
+
+func TestLoad_StatusPolicyZeroWeightPercent(t *testing.T) {
+	path := writeConfig(t, `
+[manager.status_policy]
+mode = "weighted"
+min_up_weight_percent = 0
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if p := cfg.Manager.StatusPolicy.MinUpWeightPercent; p != 0 {
+		t.Fatalf("Expected an explicit 0 to be kept, got %d", p)
+	}
+
+	cfg, err = Load(writeConfig(t, `
+[manager.status_policy]
+mode = "weighted"
+`))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if p := cfg.Manager.StatusPolicy.MinUpWeightPercent; p != 50 {
+		t.Fatalf("Expected the default of 50 when absent, got %d", p)
+	}
+}
+
+func TestLoad_InvalidStatusPolicy(t *testing.T) {
+	configs := map[string]string{
+		"unknown mode": `
+[manager.status_policy]
+mode = "majority"
+`,
+		"quorum above managers": `
+[manager]
+urls = ["http://m1:8081", "http://m2:8081"]
+
+[manager.status_policy]
+mode = "quorum"
+quorum = 3
+`,
+	}
+
+	for name, content := range configs {
+		if _, err := Load(writeConfig(t, content)); err == nil {
+			t.Errorf("Expected error for %s", name)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ManagerTargetCfg represents a single manager or other dependency to check
+type ManagerTargetCfg struct {
+	Name                  string            `toml:"name"` // defaults to the URL
+	Type                  string            `toml:"type"` // probe type, inferred from the URL scheme by default
+	URL                   string            `toml:"url"`  // base URL or address, e.g. "https://manager:8443", "tcp://db:5432"
+	HealthPath            string            `toml:"health_path"`
+	Method                string            `toml:"method"`
+	Headers               map[string]string `toml:"headers"`
+	Body                  string            `toml:"body"`
+	TimeoutSeconds        int               `toml:"timeout_seconds"` // defaults to manager.timeout_seconds
+	ExpectedStatus        []int             `toml:"expected_status"`
+	ExpectedField         string            `toml:"expected_field"` // JSON path, "-" disables the check
+	ExpectedValue         string            `toml:"expected_value"`
+	Assertions            []AssertionCfg    `toml:"assertions"`
+	MaxBodyBytes          int64             `toml:"max_body_bytes"`
+	MaxLatencyMillis      int               `toml:"max_latency_ms"`
+	DegradedLatencyMillis int               `toml:"degraded_latency_ms"` // slower successful responses are degraded
+	Weight                int               `toml:"weight"`              // share in the weighted status policy, defaults to 1
+	GRPCService           string            `toml:"grpc_service"`
+	CertMinValidDays      int               `toml:"cert_min_valid_days"`
+	ManagerTLS            bool              `toml:"manager_tls"` // https, tls and grpcs targets: use the [manager.tls] CA, client certificate and server name
+	Tags                  []string          `toml:"tags"`
+	Enabled               *bool             `toml:"enabled"` // defaults to true
+}
+
+// AssertionCfg represents an expectation on an HTTP response
+type AssertionCfg struct {
+	Source   string `toml:"source"` // "body" (default) or "header"
+	Path     string `toml:"path"`   // gjson-style body path or header name
+	Op       string `toml:"op"`     // equals (default), not_equals, contains, matches, exists, not_exists
+	Value    string `toml:"value"`
+	Severity string `toml:"severity"` // status when the assertion fails: "error" (default) or "degraded"
+}
+
+// IsEnabled reports whether the manager should be checked
+func (t ManagerTargetCfg) IsEnabled() bool {
+	return t.Enabled == nil || *t.Enabled
+}
+
+// Manager target defaults, matching the original /health contract
+const (
+	defaultHealthPath    = "/health"
+	defaultExpectedField = "status"
+	defaultExpectedValue = "success"
+)
+
+// probeTypes maps URL schemes to the probe type used when a target does not set one
+var probeTypes = map[string]string{
+	"http":       "http",
+	"https":      "http",
+	"tcp":        "tcp",
+	"tls":        "tls",
+	"dns":        "dns",
+	"grpc":       "grpc",
+	"grpcs":      "grpc",
+	"postgres":   "postgres",
+	"postgresql": "postgres",
+}
+
+// resolveManagerTargets merges structured targets with the urls shorthand,
+// applies defaults and validates the result
+func resolveManagerTargets(cfg ManagerCfg) ([]ManagerTargetCfg, error) {
+	targets := make([]ManagerTargetCfg, 0, len(cfg.Targets)+len(cfg.URLs))
+	targets = append(targets, cfg.Targets...)
+	for _, entry := range cfg.URLs {
+		name, url := ParseManagerEntry(entry)
+		targets = append(targets, ManagerTargetCfg{Name: name, URL: url})
+	}
+
+	seen := make(map[string]bool, len(targets))
+	for i := range targets {
+		t := &targets[i]
+
+		t.URL = strings.TrimRight(strings.TrimSpace(t.URL), "/")
+		if t.URL == "" {
+			return nil, fmt.Errorf("manager target %d: url is required", i+1)
+		}
+		u, err := url.ParseRequestURI(t.URL)
+		if err != nil {
+			return nil, fmt.Errorf("manager target %s: invalid url: %w", t.URL, err)
+		}
+
+		t.Type = strings.ToLower(t.Type)
+		if t.Type == "" {
+			t.Type = probeTypes[strings.ToLower(u.Scheme)]
+		}
+		if t.Type == "" {
+			return nil, fmt.Errorf("manager target %s: cannot infer probe type from scheme %q", t.URL, u.Scheme)
+		}
+		if !isProbeType(t.Type) {
+			return nil, fmt.Errorf("manager target %s: unknown probe type %q", t.URL, t.Type)
+		}
+
+		if t.Name == "" {
+			t.Name = t.URL
+		}
+		if seen[t.Name] {
+			return nil, fmt.Errorf("duplicate manager name %s", t.Name)
+		}
+		seen[t.Name] = true
+
+		if t.TimeoutSeconds == 0 {
+			t.TimeoutSeconds = cfg.TimeoutSeconds
+		}
+		if t.Weight < 0 {
+			return nil, fmt.Errorf("manager target %s: weight must not be negative", t.Name)
+		}
+		if t.Weight == 0 {
+			t.Weight = 1
+		}
+		if t.Type != "http" {
+			continue
+		}
+
+		if err := validateAssertions(t.Assertions); err != nil {
+			return nil, fmt.Errorf("manager target %s: %w", t.Name, err)
+		}
+
+		if t.HealthPath == "" {
+			t.HealthPath = defaultHealthPath
+		}
+		if !strings.HasPrefix(t.HealthPath, "/") {
+			t.HealthPath = "/" + t.HealthPath
+		}
+		t.Method = strings.ToUpper(t.Method)
+		if t.Method == "" {
+			t.Method = http.MethodGet
+		}
+		if len(t.ExpectedStatus) == 0 {
+			t.ExpectedStatus = []int{http.StatusOK}
+		}
+		switch t.ExpectedField {
+		case "":
+			t.ExpectedField = defaultExpectedField
+			if t.ExpectedValue == "" {
+				t.ExpectedValue = defaultExpectedValue
+			}
+		case "-":
+			t.ExpectedField = ""
+			t.ExpectedValue = ""
+		}
+	}
+	return targets, nil
+}
+
+// validateAssertions normalises assertion sources and operators and checks regular expressions
+func validateAssertions(assertions []AssertionCfg) error {
+	for i := range assertions {
+		a := &assertions[i]
+
+		a.Source = strings.ToLower(a.Source)
+		if a.Source == "" {
+			a.Source = "body"
+		}
+		if a.Source != "body" && a.Source != "header" {
+			return fmt.Errorf("assertion %d: unknown source %q", i+1, a.Source)
+		}
+		if a.Source == "header" && a.Path == "" {
+			return fmt.Errorf("assertion %d: header name is required", i+1)
+		}
+
+		a.Severity = strings.ToLower(a.Severity)
+		if a.Severity == "" {
+			a.Severity = "error"
+		}
+		if a.Severity != "error" && a.Severity != "degraded" {
+			return fmt.Errorf("assertion %d: unknown severity %q", i+1, a.Severity)
+		}
+
+		a.Op = strings.ToLower(a.Op)
+		switch a.Op {
+		case "":
+			a.Op = "equals"
+		case "equals", "not_equals", "contains", "exists", "not_exists":
+		case "matches":
+			if _, err := regexp.Compile(a.Value); err != nil {
+				return fmt.Errorf("assertion %d: invalid pattern: %w", i+1, err)
+			}
+		default:
+			return fmt.Errorf("assertion %d: unknown operator %q", i+1, a.Op)
+		}
+	}
+	return nil
+}
+
+func isProbeType(probeType string) bool {
+	switch probeType {
+	case "http", "tcp", "tls", "dns", "grpc", "postgres":
+		return true
+	default:
+		return false
+	}
+}
+
+// ParseManagerEntry splits a MANAGER_URLS entry of the form "name=url" or just "url".
+// A "=" that appears after the scheme belongs to the URL, e.g. in a query string.
+func ParseManagerEntry(entry string) (name, url string) {
+	entry = strings.TrimSpace(entry)
+	before, after, ok := strings.Cut(entry, "=")
+	if !ok || strings.Contains(before, "/") || strings.Contains(before, ":") {
+		return "", entry
+	}
+	return strings.TrimSpace(before), strings.TrimSpace(after)
+}
@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Shemistan/agent/internal/service"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "agent"
+
+// Metrics holds the Prometheus collectors exported on /metrics
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	managerChecks      *prometheus.CounterVec
+	managerCheckTime   *prometheus.HistogramVec
+	managerLastSuccess *prometheus.GaugeVec
+}
+
+// New creates the agent metrics and registers them together with Go runtime,
+// process and database pool collectors
+func New(db *sql.DB) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests handled, by route, method and status code.",
+		}, []string{"route", "method", "code"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency, by route and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		managerChecks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "manager_checks_total",
+			Help:      "Manager health checks performed, by manager and outcome.",
+		}, []string{"manager_url", "status"}),
+		managerCheckTime: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "manager_check_duration_seconds",
+			Help:      "Manager health check latency, by manager.",
+			Buckets:   []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+		}, []string{"manager_url"}),
+		managerLastSuccess: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "manager_last_success_timestamp_seconds",
+			Help:      "Unix time of the last successful check, by manager.",
+		}, []string{"manager_url"}),
+	}
+
+	m.registry.MustRegister(
+		m.httpRequests,
+		m.httpDuration,
+		m.managerChecks,
+		m.managerCheckTime,
+		m.managerLastSuccess,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewDBStatsCollector(db, "postgres"),
+	)
+
+	return m
+}
+
+// Handler returns the /metrics handler in Prometheus text exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// InstrumentRoute records request count and latency for a router pattern
+func (m *Metrics) InstrumentRoute(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		startedAt := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next(rec, r)
+
+		m.httpRequests.WithLabelValues(route, r.Method, strconv.Itoa(rec.status)).Inc()
+		m.httpDuration.WithLabelValues(route, r.Method).Observe(time.Since(startedAt).Seconds())
+	}
+}
+
+// ObserveManagerCheck records the outcome and latency of a manager check
+func (m *Metrics) ObserveManagerCheck(result service.ManagerCheckResult, duration time.Duration) {
+	m.managerChecks.WithLabelValues(result.ManagerURL, string(result.Status)).Inc()
+	m.managerCheckTime.WithLabelValues(result.ManagerURL).Observe(duration.Seconds())
+	if result.Status == service.StatusSuccess {
+		m.managerLastSuccess.WithLabelValues(result.ManagerURL).SetToCurrentTime()
+	}
+}
+
+// statusRecorder captures the response status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
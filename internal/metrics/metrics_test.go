@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Shemistan/agent/internal/service"
+	_ "github.com/lib/pq" // nolint:gci
+)
+
+func TestMetrics_ExposesRouteAndManagerMetrics(t *testing.T) {
+	// sql.Open does not connect, which is enough for DB pool stats
+	db, err := sql.Open("postgres", "postgres://localhost/none")
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+
+	m := New(db)
+
+	handler := m.InstrumentRoute("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	m.ObserveManagerCheck(service.ManagerCheckResult{ManagerURL: "http://m1", Status: "success"}, 50*time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+
+	for _, expected := range []string{
+		`agent_http_requests_total{code="418",method="GET",route="GET /health"} 1`,
+		`agent_http_request_duration_seconds_count{method="GET",route="GET /health"} 1`,
+		`agent_manager_checks_total{manager_url="http://m1",status="success"} 1`,
+		`agent_manager_check_duration_seconds_count{manager_url="http://m1"} 1`,
+		`agent_manager_last_success_timestamp_seconds{manager_url="http://m1"}`,
+		`go_sql_max_open_connections{db_name="postgres"}`,
+	} {
+		if !strings.Contains(string(body), expected) {
+			t.Fatalf("Expected metrics output to contain %q", expected)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Shemistan/agent/internal/service"
+)
+
+// EmailSink sends alert events as plain text email via SMTP
+type EmailSink struct {
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+// NewEmailSink creates a new EmailSink instance.
+// Authentication is only used when username is set.
+func NewEmailSink(host string, port int, username, password, from string, to []string) *EmailSink {
+	return &EmailSink{
+		addr:     net.JoinHostPort(host, strconv.Itoa(port)),
+		host:     host,
+		username: username,
+		password: password,
+		from:     from,
+		to:       to,
+	}
+}
+
+// Name implements service.AlertSink interface
+func (s *EmailSink) Name() string {
+	return "email"
+}
+
+// Send implements service.AlertSink interface.
+// net/smtp does not take a context, so cancellation is only honoured before sending.
+func (s *EmailSink) Send(ctx context.Context, event service.AlertEvent) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if s.username != "" {
+		auth = smtp.PlainAuth("", s.username, s.password, s.host)
+	}
+
+	if err := smtp.SendMail(s.addr, auth, s.from, s.to, s.message(event)); err != nil {
+		return fmt.Errorf("send mail: %w", err)
+	}
+	return nil
+}
+
+// message builds an RFC 5322 message for the event
+func (s *EmailSink) message(event service.AlertEvent) []byte {
+	subject := summary(event)
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "%s\r\n\r\n", subject)
+	fmt.Fprintf(&body, "Manager: %s\r\n", event.ManagerURL)
+	fmt.Fprintf(&body, "Event: %s\r\n", event.Kind)
+	fmt.Fprintf(&body, "At: %s\r\n", event.At.Format(time.RFC3339))
+	if !event.DownSince.IsZero() {
+		fmt.Fprintf(&body, "Down since: %s\r\n", event.DownSince.Format(time.RFC3339))
+	}
+	if event.HTTPStatus != 0 {
+		fmt.Fprintf(&body, "HTTP status: %d\r\n", event.HTTPStatus)
+	}
+	if event.LastError != "" {
+		fmt.Fprintf(&body, "Last error: %s\r\n", event.LastError)
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", s.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(s.to, ", "))
+	fmt.Fprintf(&msg, "Subject: [agent] %s\r\n", subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", event.At.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(body.String())
+	return []byte(msg.String())
+}
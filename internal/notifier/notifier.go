@@ -0,0 +1,60 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Shemistan/agent/internal/service"
+)
+
+// summary renders a one-line human readable description of an event
+func summary(event service.AlertEvent) string {
+	switch event.Kind {
+	case service.AlertDown:
+		return fmt.Sprintf("Manager %s is DOWN after %d consecutive failed checks", event.ManagerURL, event.Consecutive)
+	case service.AlertRecovered:
+		return fmt.Sprintf("Manager %s RECOVERED after %s of downtime", event.ManagerURL, downtime(event))
+	case service.AlertStillDown:
+		return fmt.Sprintf("Manager %s is STILL DOWN for %s", event.ManagerURL, downtime(event))
+	default:
+		return fmt.Sprintf("Manager %s: %s", event.ManagerURL, event.Kind)
+	}
+}
+
+func downtime(event service.AlertEvent) string {
+	if event.DownSince.IsZero() {
+		return "unknown time"
+	}
+	return event.At.Sub(event.DownSince).Round(time.Second).String()
+}
+
+// postJSON sends payload as a JSON POST request and treats non-2xx responses as errors
+func postJSON(ctx context.Context, client *http.Client, url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encode payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected HTTP status: %d", resp.StatusCode)
+	}
+	return nil
+}
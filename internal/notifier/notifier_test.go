@@ -0,0 +1,81 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Shemistan/agent/internal/service"
+)
+
+func TestWebhookSink_Send(t *testing.T) {
+	var payload webhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Expected JSON content type, got %q", r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("Failed to decode payload: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	at := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	sink := NewWebhookSink(server.Client(), server.URL)
+	err := sink.Send(context.Background(), service.AlertEvent{
+		Kind:        service.AlertDown,
+		ManagerURL:  "http://m1",
+		At:          at,
+		DownSince:   at.Add(-time.Minute),
+		Consecutive: 3,
+		LastError:   "connection refused",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if payload.Kind != service.AlertDown || payload.ManagerURL != "http://m1" || payload.Consecutive != 3 {
+		t.Errorf("Unexpected payload: %+v", payload)
+	}
+	if payload.DownSince == nil || !payload.DownSince.Equal(at.Add(-time.Minute)) {
+		t.Errorf("Expected down_since to be set, got %v", payload.DownSince)
+	}
+}
+
+func TestSlackSink_Send(t *testing.T) {
+	var payload slackPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+	}))
+	defer server.Close()
+
+	sink := NewSlackSink(server.Client(), server.URL)
+	err := sink.Send(context.Background(), service.AlertEvent{
+		Kind:       service.AlertRecovered,
+		ManagerURL: "http://m1",
+		At:         time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !strings.Contains(payload.Text, "http://m1 RECOVERED") {
+		t.Errorf("Unexpected Slack text: %q", payload.Text)
+	}
+}
+
+func TestWebhookSink_Non2xx(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.Client(), server.URL)
+	if err := sink.Send(context.Background(), service.AlertEvent{Kind: service.AlertDown}); err == nil {
+		t.Fatal("Expected error for non-2xx response")
+	}
+}
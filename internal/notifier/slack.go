@@ -0,0 +1,49 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Shemistan/agent/internal/service"
+)
+
+// SlackSink posts alert events to a Slack-compatible incoming webhook
+type SlackSink struct {
+	client *http.Client
+	url    string
+}
+
+// NewSlackSink creates a new SlackSink instance
+func NewSlackSink(client *http.Client, url string) *SlackSink {
+	return &SlackSink{
+		client: client,
+		url:    url,
+	}
+}
+
+// slackPayload represents an incoming webhook message
+type slackPayload struct {
+	Text string `json:"text"`
+}
+
+// Name implements service.AlertSink interface
+func (s *SlackSink) Name() string {
+	return "slack"
+}
+
+// Send implements service.AlertSink interface
+func (s *SlackSink) Send(ctx context.Context, event service.AlertEvent) error {
+	text := fmt.Sprintf("%s %s", slackIcon(event.Kind), summary(event))
+	if event.LastError != "" && event.Kind != service.AlertRecovered {
+		text += fmt.Sprintf("\n>Last error: %s", event.LastError)
+	}
+	return postJSON(ctx, s.client, s.url, slackPayload{Text: text})
+}
+
+func slackIcon(kind string) string {
+	if kind == service.AlertRecovered {
+		return ":white_check_mark:"
+	}
+	return ":rotating_light:"
+}
@@ -0,0 +1,57 @@
+package notifier
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Shemistan/agent/internal/service"
+)
+
+// WebhookSink posts alert events as JSON to a generic webhook
+type WebhookSink struct {
+	client *http.Client
+	url    string
+}
+
+// NewWebhookSink creates a new WebhookSink instance
+func NewWebhookSink(client *http.Client, url string) *WebhookSink {
+	return &WebhookSink{
+		client: client,
+		url:    url,
+	}
+}
+
+// webhookPayload represents the JSON body sent to a generic webhook
+type webhookPayload struct {
+	Kind        string     `json:"kind"`
+	ManagerURL  string     `json:"manager_url"`
+	At          time.Time  `json:"at"`
+	DownSince   *time.Time `json:"down_since,omitempty"`
+	Consecutive int        `json:"consecutive"`
+	LastError   string     `json:"last_error,omitempty"`
+	HTTPStatus  int        `json:"http_status,omitempty"`
+	Summary     string     `json:"summary"`
+}
+
+// Name implements service.AlertSink interface
+func (s *WebhookSink) Name() string {
+	return "webhook"
+}
+
+// Send implements service.AlertSink interface
+func (s *WebhookSink) Send(ctx context.Context, event service.AlertEvent) error {
+	payload := webhookPayload{
+		Kind:        event.Kind,
+		ManagerURL:  event.ManagerURL,
+		At:          event.At,
+		Consecutive: event.Consecutive,
+		LastError:   event.LastError,
+		HTTPStatus:  event.HTTPStatus,
+		Summary:     summary(event),
+	}
+	if !event.DownSince.IsZero() {
+		payload.DownSince = &event.DownSince
+	}
+	return postJSON(ctx, s.client, s.url, payload)
+}
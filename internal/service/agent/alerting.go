@@ -0,0 +1,332 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Shemistan/agent/internal/service"
+	"github.com/Shemistan/agent/internal/storage"
+)
+
+// Alerting states of a manager
+const (
+	alertStateUp   = "up"
+	alertStateDown = "down"
+)
+
+const (
+	// alertQueueSize bounds the number of checks waiting to be evaluated
+	alertQueueSize = 256
+	// alertSendTimeout bounds delivery of one event to one sink
+	alertSendTimeout = 10 * time.Second
+)
+
+// AlertPolicy configures when managers are considered down or recovered and how often to notify
+type AlertPolicy struct {
+	FailureThreshold  int           // consecutive failed checks before a manager is marked down
+	RecoveryThreshold int           // consecutive successful checks before it is marked up again
+	RenotifyInterval  time.Duration // reminder interval while a manager stays down, zero disables reminders
+	DedupInterval     time.Duration // suppresses repeating the same kind of notification within this interval
+}
+
+// AlertService detects manager up/down transitions from stored check history
+// and notifies the configured sinks. It is fed through service.CheckObserver.
+type AlertService struct {
+	history storage.ManagerCheckHistoryStorage
+	states  storage.AlertStateStorage
+	sinks   []service.AlertSink
+	policy  AlertPolicy
+	logger  *slog.Logger
+
+	queue chan string
+
+	mu        sync.Mutex
+	stopLoop  context.CancelFunc
+	cancelRun context.CancelFunc
+	loopDone  chan struct{}
+}
+
+// NewAlertService creates a new AlertService instance
+func NewAlertService(
+	history storage.ManagerCheckHistoryStorage,
+	states storage.AlertStateStorage,
+	sinks []service.AlertSink,
+	policy AlertPolicy,
+	logger *slog.Logger,
+) *AlertService {
+	if policy.FailureThreshold < 1 {
+		policy.FailureThreshold = 1
+	}
+	if policy.RecoveryThreshold < 1 {
+		policy.RecoveryThreshold = 1
+	}
+	return &AlertService{
+		history: history,
+		states:  states,
+		sinks:   sinks,
+		policy:  policy,
+		logger:  logger,
+		queue:   make(chan string, alertQueueSize),
+	}
+}
+
+// ObserveManagerCheck implements service.CheckObserver interface.
+// It only queues the manager for evaluation so that checks are never blocked by alerting.
+func (s *AlertService) ObserveManagerCheck(result service.ManagerCheckResult, _ time.Duration) {
+	select {
+	case s.queue <- result.ManagerURL:
+	default:
+		s.logger.Warn("alerting: queue is full, dropping check", slog.String("url", result.ManagerURL))
+	}
+}
+
+// ObserveWrittenChecks queues the managers of checks that were just stored.
+// It is used instead of ObserveManagerCheck when checks are written asynchronously,
+// so that the evaluation sees the new checks in the history.
+func (s *AlertService) ObserveWrittenChecks(checks []storage.ManagerCheck) {
+	seen := make(map[string]bool, len(checks))
+	for _, check := range checks {
+		if seen[check.ManagerURL] {
+			continue
+		}
+		seen[check.ManagerURL] = true
+		s.ObserveManagerCheck(service.ManagerCheckResult{ManagerURL: check.ManagerURL}, 0)
+	}
+}
+
+// Start launches the evaluation loop in the background and returns immediately
+func (s *AlertService) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopLoop != nil {
+		return
+	}
+
+	loopCtx, stopLoop := context.WithCancel(ctx)
+	// Evaluations are not tied to the loop so that Stop can let an in-flight one finish
+	runCtx, cancelRun := context.WithCancel(context.WithoutCancel(ctx))
+
+	s.stopLoop = stopLoop
+	s.cancelRun = cancelRun
+	s.loopDone = make(chan struct{})
+
+	go s.loop(loopCtx, runCtx)
+
+	s.logger.Info("alerting: started",
+		slog.Int("sinks", len(s.sinks)),
+		slog.Int("failure_threshold", s.policy.FailureThreshold),
+		slog.Int("recovery_threshold", s.policy.RecoveryThreshold),
+	)
+}
+
+// Stop evaluates the checks that are already queued and stops the loop.
+// If ctx expires first, the remaining queue is abandoned and the in-flight evaluation cancelled.
+func (s *AlertService) Stop(ctx context.Context) {
+	s.mu.Lock()
+	stopLoop, cancelRun, loopDone := s.stopLoop, s.cancelRun, s.loopDone
+	s.mu.Unlock()
+
+	if stopLoop == nil {
+		return
+	}
+
+	s.drain(ctx)
+	stopLoop()
+	select {
+	case <-loopDone:
+	case <-ctx.Done():
+		cancelRun()
+		<-loopDone
+	}
+	cancelRun()
+
+	s.logger.Info("alerting: stopped")
+}
+
+// loop evaluates queued managers one at a time, so that state updates never race
+func (s *AlertService) loop(loopCtx, runCtx context.Context) {
+	defer close(s.loopDone)
+
+	for {
+		select {
+		case <-loopCtx.Done():
+			return
+		case managerURL := <-s.queue:
+			s.evaluate(runCtx, managerURL)
+		}
+	}
+}
+
+// drain waits until the queue is empty or ctx expires
+func (s *AlertService) drain(ctx context.Context) {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for len(s.queue) > 0 {
+		select {
+		case <-ctx.Done():
+			s.logger.Warn("alerting: abandoning queued checks on shutdown", slog.Int("queued", len(s.queue)))
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// evaluate compares the recent check history of a manager with its saved state
+// and sends a notification on a transition or when a reminder is due
+func (s *AlertService) evaluate(ctx context.Context, managerURL string) {
+	now := time.Now()
+
+	checks, err := s.history.ListManagerChecks(ctx, storage.ManagerCheckFilter{
+		ManagerURL: managerURL,
+		Limit:      max(s.policy.FailureThreshold, s.policy.RecoveryThreshold),
+	})
+	if err != nil {
+		s.logger.Error("alerting: failed to load check history", slog.String("url", managerURL), slog.String("error", err.Error()))
+		return
+	}
+	if len(checks) == 0 {
+		return
+	}
+
+	state, found, err := s.states.GetAlertState(ctx, managerURL)
+	if err != nil {
+		s.logger.Error("alerting: failed to load alert state", slog.String("url", managerURL), slog.String("error", err.Error()))
+		return
+	}
+	if !found {
+		state = storage.AlertState{ManagerURL: managerURL, State: alertStateUp, ChangedAt: now}
+	}
+
+	up, streak := leadingStreak(checks)
+	latest := checks[0]
+	event := service.AlertEvent{
+		ManagerURL:  managerURL,
+		At:          now,
+		Consecutive: streak,
+	}
+	if latest.ErrorMessage != nil {
+		event.LastError = *latest.ErrorMessage
+	}
+	if latest.HTTPStatus != nil {
+		event.HTTPStatus = *latest.HTTPStatus
+	}
+
+	switch {
+	case state.State != alertStateDown && !up && streak >= s.policy.FailureThreshold:
+		event.Kind = service.AlertDown
+		event.DownSince = checks[streak-1].CheckedAt
+		state.State = alertStateDown
+		state.ChangedAt = event.DownSince
+	case state.State == alertStateDown && up && streak >= s.policy.RecoveryThreshold:
+		event.Kind = service.AlertRecovered
+		event.DownSince = state.ChangedAt
+		state.State = alertStateUp
+		state.ChangedAt = checks[streak-1].CheckedAt
+	case state.State == alertStateDown && s.reminderDue(state, now):
+		event.Kind = service.AlertStillDown
+		event.DownSince = state.ChangedAt
+	default:
+		return
+	}
+
+	if s.duplicate(state, event.Kind, now) {
+		s.logger.Info("alerting: suppressing duplicate notification",
+			slog.String("url", managerURL),
+			slog.String("kind", event.Kind),
+		)
+	} else if s.notify(ctx, event) {
+		state.LastNotifiedAt = &now
+		switch event.Kind {
+		case service.AlertDown:
+			state.DownNotifiedAt = &now
+		case service.AlertRecovered:
+			state.RecoveredNotifiedAt = &now
+		}
+	}
+
+	if err := s.states.SaveAlertState(ctx, state); err != nil {
+		s.logger.Error("alerting: failed to save alert state", slog.String("url", managerURL), slog.String("error", err.Error()))
+	}
+}
+
+// reminderDue reports whether a still-down reminder should be sent
+func (s *AlertService) reminderDue(state storage.AlertState, now time.Time) bool {
+	if s.policy.RenotifyInterval <= 0 {
+		return false
+	}
+	if state.LastNotifiedAt == nil {
+		return true
+	}
+	return now.Sub(*state.LastNotifiedAt) >= s.policy.RenotifyInterval
+}
+
+// duplicate reports whether a transition of the same kind was already notified within
+// the dedup interval, e.g. when a flapping manager goes down again right after recovering.
+// Reminders are paced by the renotify interval instead.
+func (s *AlertService) duplicate(state storage.AlertState, kind string, now time.Time) bool {
+	var last *time.Time
+	switch kind {
+	case service.AlertDown:
+		last = state.DownNotifiedAt
+	case service.AlertRecovered:
+		last = state.RecoveredNotifiedAt
+	}
+	if s.policy.DedupInterval <= 0 || last == nil {
+		return false
+	}
+	return now.Sub(*last) < s.policy.DedupInterval
+}
+
+// notify sends the event to every sink and reports whether at least one delivery succeeded
+func (s *AlertService) notify(ctx context.Context, event service.AlertEvent) bool {
+	s.logger.Warn("alerting: manager state changed",
+		slog.String("url", event.ManagerURL),
+		slog.String("kind", event.Kind),
+		slog.Int("consecutive", event.Consecutive),
+	)
+	if len(s.sinks) == 0 {
+		return true
+	}
+
+	delivered := false
+	for _, sink := range s.sinks {
+		if err := s.send(ctx, sink, event); err != nil {
+			s.logger.Error("alerting: failed to send notification",
+				slog.String("sink", sink.Name()),
+				slog.String("url", event.ManagerURL),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+		delivered = true
+	}
+	return delivered
+}
+
+func (s *AlertService) send(ctx context.Context, sink service.AlertSink, event service.AlertEvent) error {
+	sendCtx, cancel := context.WithTimeout(ctx, alertSendTimeout)
+	defer cancel()
+
+	if err := sink.Send(sendCtx, event); err != nil {
+		return fmt.Errorf("%s: %w", sink.Name(), err)
+	}
+	return nil
+}
+
+// leadingStreak returns whether the newest check was up (healthy or degraded) and how many
+// consecutive checks, newest first, share that outcome
+func leadingStreak(checks []storage.ManagerCheck) (up bool, streak int) {
+	up = service.Status(checks[0].Status).IsUp()
+	for _, check := range checks {
+		if service.Status(check.Status).IsUp() != up {
+			break
+		}
+		streak++
+	}
+	return up, streak
+}
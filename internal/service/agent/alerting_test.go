@@ -0,0 +1,156 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Shemistan/agent/internal/service"
+	"github.com/Shemistan/agent/internal/storage"
+)
+
+// MockAlertStateStorage implements storage.AlertStateStorage interface
+type MockAlertStateStorage struct {
+	states map[string]storage.AlertState
+}
+
+func (m *MockAlertStateStorage) GetAlertState(ctx context.Context, managerURL string) (storage.AlertState, bool, error) {
+	state, ok := m.states[managerURL]
+	return state, ok, nil
+}
+
+func (m *MockAlertStateStorage) SaveAlertState(ctx context.Context, state storage.AlertState) error {
+	m.states[state.ManagerURL] = state
+	return nil
+}
+
+// MockAlertSink implements service.AlertSink interface
+type MockAlertSink struct {
+	events []service.AlertEvent
+}
+
+func (m *MockAlertSink) Name() string {
+	return "mock"
+}
+
+func (m *MockAlertSink) Send(ctx context.Context, event service.AlertEvent) error {
+	m.events = append(m.events, event)
+	return nil
+}
+
+// alertFixture feeds checks to an AlertService one at a time, like the observer does
+type alertFixture struct {
+	history *MockHistoryStorage
+	sink    *MockAlertSink
+	service *AlertService
+	nextID  int64
+}
+
+func newAlertFixture(policy AlertPolicy) *alertFixture {
+	history := &MockHistoryStorage{}
+	sink := &MockAlertSink{}
+	states := &MockAlertStateStorage{states: make(map[string]storage.AlertState)}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	return &alertFixture{
+		history: history,
+		sink:    sink,
+		service: NewAlertService(history, states, []service.AlertSink{sink}, policy, logger),
+	}
+}
+
+func (f *alertFixture) check(status string) {
+	f.nextID++
+	check := storage.ManagerCheck{
+		ID:         f.nextID,
+		CheckedAt:  time.Now(),
+		ManagerURL: "http://m1",
+		Status:     status,
+	}
+	f.history.checks = append([]storage.ManagerCheck{check}, f.history.checks...)
+	f.service.evaluate(context.Background(), "http://m1")
+}
+
+func (f *alertFixture) kinds() []string {
+	kinds := make([]string, 0, len(f.sink.events))
+	for _, event := range f.sink.events {
+		kinds = append(kinds, event.Kind)
+	}
+	return kinds
+}
+
+func TestAlertService_Transitions(t *testing.T) {
+	f := newAlertFixture(AlertPolicy{FailureThreshold: 3, RecoveryThreshold: 2})
+
+	f.check("error")
+	f.check("error")
+	if len(f.sink.events) != 0 {
+		t.Fatalf("Expected no alert before the failure threshold, got %v", f.kinds())
+	}
+
+	f.check("error")
+	if len(f.sink.events) != 1 || f.sink.events[0].Kind != service.AlertDown {
+		t.Fatalf("Expected a down alert, got %v", f.kinds())
+	}
+	if f.sink.events[0].Consecutive != 3 {
+		t.Errorf("Expected 3 consecutive failures, got %d", f.sink.events[0].Consecutive)
+	}
+
+	f.check("error")
+	f.check("success")
+	if len(f.sink.events) != 1 {
+		t.Fatalf("Expected no alert before the recovery threshold, got %v", f.kinds())
+	}
+
+	f.check("success")
+	if len(f.sink.events) != 2 || f.sink.events[1].Kind != service.AlertRecovered {
+		t.Fatalf("Expected a recovered alert, got %v", f.kinds())
+	}
+	if f.sink.events[1].DownSince.IsZero() {
+		t.Error("Expected recovered alert to carry the down time")
+	}
+}
+
+func TestAlertService_DedupSuppressesFlapping(t *testing.T) {
+	f := newAlertFixture(AlertPolicy{FailureThreshold: 1, RecoveryThreshold: 1, DedupInterval: time.Hour})
+
+	f.check("error")
+	f.check("success")
+	f.check("error")
+	f.check("success")
+
+	expected := []string{service.AlertDown, service.AlertRecovered}
+	if got := f.kinds(); len(got) != len(expected) || got[0] != expected[0] || got[1] != expected[1] {
+		t.Fatalf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestAlertService_Renotify(t *testing.T) {
+	f := newAlertFixture(AlertPolicy{FailureThreshold: 1, RecoveryThreshold: 1, RenotifyInterval: 20 * time.Millisecond})
+
+	f.check("error")
+	f.check("error")
+	if len(f.sink.events) != 1 {
+		t.Fatalf("Expected no reminder before the renotify interval, got %v", f.kinds())
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	f.check("error")
+	if len(f.sink.events) != 2 || f.sink.events[1].Kind != service.AlertStillDown {
+		t.Fatalf("Expected a still_down reminder, got %v", f.kinds())
+	}
+}
+
+func TestAlertService_StopDrainsQueue(t *testing.T) {
+	f := newAlertFixture(AlertPolicy{FailureThreshold: 1, RecoveryThreshold: 1})
+	f.history.checks = []storage.ManagerCheck{{ID: 1, ManagerURL: "http://m1", Status: "error"}}
+
+	f.service.Start(context.Background())
+	f.service.ObserveManagerCheck(service.ManagerCheckResult{ManagerURL: "http://m1", Status: "error"}, 0)
+	f.service.Stop(context.Background())
+
+	if len(f.sink.events) != 1 {
+		t.Fatalf("Expected queued check to be evaluated before Stop returns, got %v", f.kinds())
+	}
+}
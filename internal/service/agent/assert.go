@@ -0,0 +1,276 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Shemistan/agent/internal/service"
+)
+
+// pathMatch is a value found at a concrete path of a JSON document
+type pathMatch struct {
+	path  string
+	value any
+}
+
+// checkAssertions evaluates assertions against a response and returns the ones that did not hold.
+// doc is the decoded JSON body, nil when no body assertions are configured.
+func checkAssertions(assertions []service.Assertion, header http.Header, doc any) []service.AssertionFailure {
+	var failures []service.AssertionFailure
+	for _, a := range assertions {
+		if a.Op == "" {
+			a.Op = service.OpEquals
+		}
+		if a.Source == "" {
+			a.Source = service.AssertBody
+		}
+		if a.Severity == "" {
+			a.Severity = service.StatusError
+		}
+
+		var matches []pathMatch
+		if a.Source == service.AssertHeader {
+			if values := header.Values(a.Path); len(values) > 0 {
+				matches = []pathMatch{{path: a.Path, value: strings.Join(values, ", ")}}
+			}
+		} else {
+			matches = lookupPath(doc, a.Path)
+		}
+		failures = append(failures, checkMatches(a, matches)...)
+	}
+	return failures
+}
+
+// checkMatches applies the assertion operator to every matched value
+func checkMatches(a service.Assertion, matches []pathMatch) []service.AssertionFailure {
+	failure := func(path string, actual any, missing bool) service.AssertionFailure {
+		f := service.AssertionFailure{
+			Source:   a.Source,
+			Path:     path,
+			Op:       a.Op,
+			Expected: a.Value,
+			Missing:  missing,
+			Severity: a.Severity,
+		}
+		if !missing {
+			f.Actual = formatValue(actual)
+		}
+		return f
+	}
+
+	switch a.Op {
+	case service.OpExists:
+		if len(matches) == 0 {
+			return []service.AssertionFailure{failure(a.Path, nil, true)}
+		}
+		return nil
+	case service.OpNotExists:
+		var failures []service.AssertionFailure
+		for _, m := range matches {
+			failures = append(failures, failure(m.path, m.value, false))
+		}
+		return failures
+	}
+
+	if len(matches) == 0 {
+		return []service.AssertionFailure{failure(a.Path, nil, true)}
+	}
+
+	var failures []service.AssertionFailure
+	for _, m := range matches {
+		ok, err := holds(a, m.value)
+		if err != nil {
+			f := failure(m.path, m.value, false)
+			f.Actual = err.Error()
+			failures = append(failures, f)
+			continue
+		}
+		if !ok {
+			failures = append(failures, failure(m.path, m.value, false))
+		}
+	}
+	return failures
+}
+
+// compileAssertions returns a copy of assertions with Pattern set for every valid matches assertion
+func compileAssertions(assertions []service.Assertion) []service.Assertion {
+	if assertions == nil {
+		return nil
+	}
+	compiled := make([]service.Assertion, len(assertions))
+	for i, a := range assertions {
+		if a.Op == service.OpMatches && a.Pattern == nil {
+			a.Pattern, _ = regexp.Compile(a.Value)
+		}
+		compiled[i] = a
+	}
+	return compiled
+}
+
+// holds reports whether a single value satisfies the assertion operator
+func holds(a service.Assertion, value any) (bool, error) {
+	expected := a.Value
+	switch a.Op {
+	case service.OpEquals:
+		return formatValue(value) == expected, nil
+	case service.OpNotEquals:
+		return formatValue(value) != expected, nil
+	case service.OpContains:
+		switch v := value.(type) {
+		case []any:
+			for _, item := range v {
+				if formatValue(item) == expected {
+					return true, nil
+				}
+			}
+			return false, nil
+		case map[string]any:
+			_, ok := v[expected]
+			return ok, nil
+		default:
+			return strings.Contains(formatValue(value), expected), nil
+		}
+	case service.OpMatches:
+		re := a.Pattern
+		if re == nil {
+			var err error
+			if re, err = regexp.Compile(expected); err != nil {
+				return false, fmt.Errorf("invalid pattern: %w", err)
+			}
+		}
+		return re.MatchString(formatValue(value)), nil
+	default:
+		return false, fmt.Errorf("unknown operator %s", a.Op)
+	}
+}
+
+// lookupPath resolves a gjson-style path: dot separated keys (a "\." is a literal dot),
+// array indexes, "*" for every child of an object or array and "#" for the length of an array.
+// An empty path matches the whole document.
+func lookupPath(doc any, path string) []pathMatch {
+	matches := []pathMatch{{value: doc}}
+	if path == "" {
+		return matches
+	}
+
+	for _, segment := range splitPath(path) {
+		var next []pathMatch
+		for _, m := range matches {
+			next = append(next, step(m, segment)...)
+		}
+		matches = next
+	}
+	return matches
+}
+
+// step descends one path segment from a match
+func step(m pathMatch, segment string) []pathMatch {
+	child := func(key string, value any) pathMatch {
+		if m.path == "" {
+			return pathMatch{path: key, value: value}
+		}
+		return pathMatch{path: m.path + "." + key, value: value}
+	}
+
+	switch v := m.value.(type) {
+	case map[string]any:
+		if segment == "*" {
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			matches := make([]pathMatch, 0, len(keys))
+			for _, key := range keys {
+				matches = append(matches, child(key, v[key]))
+			}
+			return matches
+		}
+		if value, ok := v[segment]; ok {
+			return []pathMatch{child(segment, value)}
+		}
+	case []any:
+		switch segment {
+		case "*":
+			matches := make([]pathMatch, 0, len(v))
+			for i, value := range v {
+				matches = append(matches, child(strconv.Itoa(i), value))
+			}
+			return matches
+		case "#":
+			return []pathMatch{child("#", float64(len(v)))}
+		}
+		if i, err := strconv.Atoi(segment); err == nil && i >= 0 && i < len(v) {
+			return []pathMatch{child(segment, v[i])}
+		}
+	}
+	return nil
+}
+
+// splitPath splits a path on dots that are not escaped with a backslash
+func splitPath(path string) []string {
+	var (
+		segments []string
+		current  strings.Builder
+	)
+	for i := 0; i < len(path); i++ {
+		switch {
+		case path[i] == '\\' && i+1 < len(path):
+			i++
+			current.WriteByte(path[i])
+		case path[i] == '.':
+			segments = append(segments, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(path[i])
+		}
+	}
+	return append(segments, current.String())
+}
+
+// formatValue renders a decoded JSON value for comparison: strings as is, everything else as JSON
+func formatValue(value any) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// hasBodyAssertions reports whether any assertion needs the response body
+func hasBodyAssertions(assertions []service.Assertion) bool {
+	for _, a := range assertions {
+		if a.Source == "" || a.Source == service.AssertBody {
+			return true
+		}
+	}
+	return false
+}
+
+// assertionStatus returns the status of a check with failed assertions:
+// degraded when every failure has degraded severity, error otherwise
+func assertionStatus(failures []service.AssertionFailure) service.Status {
+	for _, f := range failures {
+		if f.Severity != service.StatusDegraded {
+			return service.StatusError
+		}
+	}
+	return service.StatusDegraded
+}
+
+// assertionError summarises failed assertions for ErrorMessage
+func assertionError(failures []service.AssertionFailure) string {
+	parts := make([]string, 0, len(failures))
+	for _, f := range failures {
+		parts = append(parts, f.String())
+	}
+	return fmt.Sprintf("%d assertion(s) failed: %s", len(failures), strings.Join(parts, "; "))
+}
@@ -0,0 +1,130 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Shemistan/agent/internal/service"
+)
+
+func decodeJSON(t *testing.T, body string) any {
+	t.Helper()
+
+	var doc any
+	decoder := json.NewDecoder(bytes.NewReader([]byte(body)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		t.Fatalf("failed to decode %s: %v", body, err)
+	}
+	return doc
+}
+
+func TestLookupPath(t *testing.T) {
+	doc := decodeJSON(t, `{
+		"status": "ok",
+		"components": {"db": "ok", "queue": "degraded"},
+		"nodes": [{"id": 1, "up": true}, {"id": 2, "up": false}],
+		"a.b": "dotted"
+	}`)
+
+	tests := []struct {
+		path     string
+		expected []string // "<path>=<value>"
+	}{
+		{"status", []string{"status=ok"}},
+		{"components.*", []string{"components.db=ok", "components.queue=degraded"}},
+		{"nodes.1.id", []string{"nodes.1.id=2"}},
+		{"nodes.*.up", []string{"nodes.0.up=true", "nodes.1.up=false"}},
+		{"nodes.#", []string{"nodes.#=2"}},
+		{`a\.b`, []string{"a.b=dotted"}},
+		{"missing.field", nil},
+		{"nodes.5", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, m := range lookupPath(doc, tt.path) {
+			got = append(got, m.path+"="+formatValue(m.value))
+		}
+		if len(got) != len(tt.expected) {
+			t.Errorf("lookupPath(%q) = %v, want %v", tt.path, got, tt.expected)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.expected[i] {
+				t.Errorf("lookupPath(%q) = %v, want %v", tt.path, got, tt.expected)
+				break
+			}
+		}
+	}
+}
+
+func TestCheckAssertions(t *testing.T) {
+	doc := decodeJSON(t, `{"version": "1.4.2", "components": {"db": "ok", "queue": "degraded"}, "roles": ["api", "worker"]}`)
+	header := http.Header{"Content-Type": []string{"application/json; charset=utf-8"}}
+
+	passing := []service.Assertion{
+		{Path: "version", Op: service.OpMatches, Value: `^1\.\d+\.\d+$`},
+		{Path: "roles", Op: service.OpContains, Value: "worker"},
+		{Path: "components", Op: service.OpContains, Value: "db"},
+		{Path: "components.cache", Op: service.OpNotExists},
+		{Source: service.AssertHeader, Path: "Content-Type", Op: service.OpContains, Value: "json"},
+	}
+	if failures := checkAssertions(passing, header, doc); len(failures) != 0 {
+		t.Fatalf("Expected all assertions to hold, got %v", failures)
+	}
+
+	failing := []service.Assertion{
+		{Path: "components.*", Value: "ok"},
+		{Path: "uptime", Op: service.OpExists},
+		{Source: service.AssertHeader, Path: "X-Version", Value: "2"},
+	}
+	failures := checkAssertions(failing, header, doc)
+	if len(failures) != 3 {
+		t.Fatalf("Expected 3 failures, got %v", failures)
+	}
+
+	if failures[0].Path != "components.queue" || failures[0].Actual != "degraded" {
+		t.Errorf("Expected the degraded component to be reported, got %+v", failures[0])
+	}
+	if !failures[1].Missing || !failures[2].Missing {
+		t.Errorf("Expected missing field and header, got %+v and %+v", failures[1], failures[2])
+	}
+
+	expected := `body components.queue equals "ok": got "degraded"`
+	if failures[0].String() != expected {
+		t.Errorf("Expected %q, got %q", expected, failures[0].String())
+	}
+}
+
+func TestCompileTargets(t *testing.T) {
+	targets := []service.ManagerTarget{{
+		URL: "http://manager",
+		Assertions: []service.Assertion{
+			{Path: "version", Op: service.OpMatches, Value: `^1\.\d+$`},
+			{Path: "build", Op: service.OpMatches, Value: `(`},
+			{Path: "status", Value: "success"},
+		},
+	}}
+
+	compiled := compileTargets(targets)
+
+	if targets[0].Assertions[0].Pattern != nil {
+		t.Error("Expected the configured targets to be left unchanged")
+	}
+	assertions := compiled[0].Assertions
+	if assertions[0].Pattern == nil || assertions[0].Pattern.String() != `^1\.\d+$` {
+		t.Fatalf("Expected the matches pattern to be compiled, got %v", assertions[0].Pattern)
+	}
+	if assertions[1].Pattern != nil || assertions[2].Pattern != nil {
+		t.Errorf("Expected no pattern for invalid and non-matches assertions, got %+v", assertions)
+	}
+
+	doc := decodeJSON(t, `{"version": "1.4", "build": "abc", "status": "success"}`)
+	failures := checkAssertions(assertions, http.Header{}, doc)
+	if len(failures) != 1 || failures[0].Path != "build" || !strings.HasPrefix(failures[0].Actual, "invalid pattern") {
+		t.Errorf("Expected only the invalid pattern to fail, got %+v", failures)
+	}
+}
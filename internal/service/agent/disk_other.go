@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package agent
+
+import (
+	"errors"
+	"fmt"
+)
+
+// diskSpace is not implemented on this platform
+func diskSpace(path string) (free, total uint64, err error) {
+	return 0, 0, fmt.Errorf("disk space of %s: %w", path, errors.ErrUnsupported)
+}
@@ -0,0 +1,17 @@
+//go:build linux || darwin
+
+package agent
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// diskSpace returns the bytes available to unprivileged users and the size of the file system containing path
+func diskSpace(path string) (free, total uint64, err error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, 0, fmt.Errorf("statfs %s: %w", path, err)
+	}
+	return st.Bavail * uint64(st.Bsize), st.Blocks * uint64(st.Bsize), nil // nolint:gosec // block size is positive
+}
@@ -0,0 +1,156 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Shemistan/agent/internal/service"
+	"github.com/Shemistan/agent/internal/storage"
+)
+
+// HealthCallPolicy configures sampling and buffering of recorded /health calls
+type HealthCallPolicy struct {
+	SampleEvery int           // record one call in SampleEvery, 1 records every call
+	MinInterval time.Duration // record at most one call per interval, zero means no limit
+	BufferSize  int           // sampled calls waiting to be written; further calls are dropped
+}
+
+// HealthService implements the health check service. Calls are sampled and written to storage
+// in the background, so a slow or unavailable database never affects /health responses.
+// Every stored row counts the calls skipped since the previous one.
+type HealthService struct {
+	healthStorage storage.HealthStorage
+	policy        HealthCallPolicy
+	logger        *slog.Logger
+
+	mu           sync.Mutex
+	skipped      int                // calls seen since the last sampled one
+	lastSkipped  service.HealthCall // most recent of the skipped calls
+	lastRecorded time.Time
+	stopped      bool
+
+	queue     chan storage.HealthCall
+	startOnce sync.Once
+	saveCtx   context.Context
+	cancel    context.CancelFunc
+	loopDone  chan struct{}
+	dropped   atomic.Int64
+}
+
+// NewHealthService creates a new HealthService instance
+func NewHealthService(healthStorage storage.HealthStorage, policy HealthCallPolicy, logger *slog.Logger) *HealthService {
+	if policy.SampleEvery <= 0 {
+		policy.SampleEvery = 1
+	}
+	if policy.BufferSize <= 0 {
+		policy.BufferSize = 1000
+	}
+	saveCtx, cancel := context.WithCancel(context.Background())
+	return &HealthService{
+		healthStorage: healthStorage,
+		policy:        policy,
+		logger:        logger,
+		queue:         make(chan storage.HealthCall, policy.BufferSize),
+		saveCtx:       saveCtx,
+		cancel:        cancel,
+		loopDone:      make(chan struct{}),
+	}
+}
+
+// Start starts writing sampled calls in the background
+func (s *HealthService) Start() {
+	s.startOnce.Do(func() {
+		go s.loop()
+	})
+}
+
+// RecordHealthCall implements service.HealthService interface.
+// It samples the call and queues it for writing without blocking.
+func (s *HealthService) RecordHealthCall(call service.HealthCall) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return
+	}
+
+	s.skipped++
+	if s.skipped < s.policy.SampleEvery || call.CalledAt.Sub(s.lastRecorded) < s.policy.MinInterval {
+		s.lastSkipped = call
+		return
+	}
+
+	s.enqueue(call, s.skipped)
+	s.skipped = 0
+	s.lastRecorded = call.CalledAt
+}
+
+// Stop records the count of calls skipped since the last sampled one, then waits
+// until queued calls are written. If ctx expires first, the remaining calls are lost.
+func (s *HealthService) Stop(ctx context.Context) {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	s.stopped = true
+	if s.skipped > 0 {
+		s.enqueue(s.lastSkipped, s.skipped)
+	}
+	close(s.queue)
+	s.mu.Unlock()
+
+	// Drain the queue even if the service was never started
+	s.Start()
+
+	select {
+	case <-s.loopDone:
+	case <-ctx.Done():
+		s.logger.Warn("health calls: abandoning queued calls on shutdown", slog.Int("queued", len(s.queue)))
+		s.cancel()
+		<-s.loopDone
+	}
+	s.cancel()
+	if dropped := s.dropped.Load(); dropped > 0 {
+		s.logger.Warn("health calls: calls were not recorded", slog.Int64("dropped", dropped))
+	}
+}
+
+// enqueue queues a sampled call that stands for calls calls; mu must be held
+func (s *HealthService) enqueue(call service.HealthCall, calls int) {
+	record := storage.HealthCall{
+		CalledAt: call.CalledAt,
+		Calls:    calls,
+	}
+	if ip := net.ParseIP(call.CallerIP); ip != nil {
+		callerIP := ip.String()
+		record.CallerIP = &callerIP
+	}
+	if call.UserAgent != "" {
+		record.UserAgent = &call.UserAgent
+	}
+	record.LatencyMillis = millis(call.Latency)
+
+	select {
+	case s.queue <- record:
+	default:
+		s.dropped.Add(int64(calls))
+	}
+}
+
+func (s *HealthService) loop() {
+	defer close(s.loopDone)
+
+	for call := range s.queue {
+		ctx, cancel := context.WithTimeout(s.saveCtx, 5*time.Second)
+		if err := s.healthStorage.SaveHealthCall(ctx, call); err != nil {
+			s.dropped.Add(int64(call.Calls))
+			s.logger.Error("health calls: failed to save health call", slog.String("error", err.Error()))
+		}
+		cancel()
+	}
+}
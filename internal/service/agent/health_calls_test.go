@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Shemistan/agent/internal/service"
+)
+
+func TestHealthService_SamplesOneInN(t *testing.T) {
+	mockStorage := &MockHealthStorage{}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	healthService := NewHealthService(mockStorage, HealthCallPolicy{SampleEvery: 3}, logger)
+	healthService.Start()
+
+	start := time.Now()
+	for i := 0; i < 7; i++ {
+		healthService.RecordHealthCall(service.HealthCall{CalledAt: start.Add(time.Duration(i) * time.Millisecond)})
+	}
+	healthService.Stop(context.Background())
+
+	// Two sampled calls and, on stop, the last skipped call counting the remainder
+	expected := []int{3, 3, 1}
+	if len(mockStorage.calls) != len(expected) {
+		t.Fatalf("Expected %d rows, got %+v", len(expected), mockStorage.calls)
+	}
+	total := 0
+	for i, calls := range expected {
+		if mockStorage.calls[i].Calls != calls {
+			t.Errorf("Row %d: expected to stand for %d calls, got %d", i, calls, mockStorage.calls[i].Calls)
+		}
+		total += mockStorage.calls[i].Calls
+	}
+	if total != 7 {
+		t.Errorf("Expected rows to count all 7 calls, got %d", total)
+	}
+}
+
+func TestHealthService_SamplesAtMostOncePerInterval(t *testing.T) {
+	mockStorage := &MockHealthStorage{}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	healthService := NewHealthService(mockStorage, HealthCallPolicy{MinInterval: time.Second}, logger)
+	healthService.Start()
+
+	start := time.Now()
+	for _, offset := range []time.Duration{0, 200, 400, 1000, 1500, 2100} {
+		healthService.RecordHealthCall(service.HealthCall{CalledAt: start.Add(offset * time.Millisecond)})
+	}
+	healthService.Stop(context.Background())
+
+	expected := []struct {
+		offset time.Duration
+		calls  int
+	}{
+		{0, 1},
+		{1000 * time.Millisecond, 3},
+		{2100 * time.Millisecond, 2},
+	}
+	if len(mockStorage.calls) != len(expected) {
+		t.Fatalf("Expected %d rows, got %+v", len(expected), mockStorage.calls)
+	}
+	for i, row := range expected {
+		call := mockStorage.calls[i]
+		if !call.CalledAt.Equal(start.Add(row.offset)) || call.Calls != row.calls {
+			t.Errorf("Row %d: expected call at +%v standing for %d calls, got +%v for %d",
+				i, row.offset, row.calls, call.CalledAt.Sub(start), call.Calls)
+		}
+	}
+}
+
+func TestHealthService_StorageErrorDoesNotBlock(t *testing.T) {
+	mockStorage := &MockHealthStorage{err: errors.New("connection refused")}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	healthService := NewHealthService(mockStorage, HealthCallPolicy{BufferSize: 1}, logger)
+
+	// Not started: the buffer fills up and further calls are dropped without blocking
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			healthService.RecordHealthCall(service.HealthCall{CalledAt: time.Now(), CallerIP: "not an ip"})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected RecordHealthCall not to block on a full buffer")
+	}
+
+	healthService.Stop(context.Background())
+	if len(mockStorage.calls) != 0 {
+		t.Errorf("Expected no calls stored, got %d", len(mockStorage.calls))
+	}
+}
@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Shemistan/agent/internal/service"
+	"github.com/Shemistan/agent/internal/storage"
+)
+
+// DatabaseCheck fails when no database connection can be obtained and pinged in time,
+// e.g. when the pool is exhausted
+func DatabaseCheck(schemaStorage storage.SchemaStorage) HealthCheckFunc {
+	return func(ctx context.Context) (map[string]any, error) {
+		return nil, schemaStorage.Ping(ctx)
+	}
+}
+
+// MigrationCheck fails when the database schema is older than the latest migration
+// the binary was built with. A newer schema, e.g. during a rolling update, is accepted.
+func MigrationCheck(schemaStorage storage.SchemaStorage, expected int64) HealthCheckFunc {
+	return func(ctx context.Context) (map[string]any, error) {
+		details := map[string]any{"expected_version": expected}
+
+		current, err := schemaStorage.SchemaVersion(ctx)
+		if err != nil {
+			return details, err
+		}
+		details["current_version"] = current
+
+		if current < expected {
+			return details, fmt.Errorf("schema version %d is behind %d expected by the binary", current, expected)
+		}
+		return details, nil
+	}
+}
+
+// SchedulerCheck fails when the enabled scheduler has not started a run for longer than maxAge,
+// e.g. because a run hangs. Before the first run the age is counted from since.
+func SchedulerCheck(scheduler service.Scheduler, maxAge time.Duration, since time.Time) HealthCheckFunc {
+	return func(context.Context) (map[string]any, error) {
+		status := scheduler.Status()
+		details := map[string]any{"enabled": status.Enabled, "running": status.Running}
+		if !status.Enabled {
+			return details, nil
+		}
+
+		lastRunAt := status.LastRunAt
+		if lastRunAt.IsZero() {
+			lastRunAt = since
+		} else {
+			details["last_run_at"] = status.LastRunAt
+		}
+
+		age := time.Since(lastRunAt)
+		details["last_run_age_seconds"] = age.Seconds()
+		details["max_age_seconds"] = maxAge.Seconds()
+		if age > maxAge {
+			return details, fmt.Errorf("no scheduler run for %s, limit %s", age.Round(time.Second), maxAge)
+		}
+		return details, nil
+	}
+}
+
+// DiskCheck fails when the file system containing path has less than minFree bytes available
+func DiskCheck(path string, minFree uint64) HealthCheckFunc {
+	return func(context.Context) (map[string]any, error) {
+		details := map[string]any{"path": path, "min_free_bytes": minFree}
+
+		free, total, err := diskSpace(path)
+		if err != nil {
+			return details, err
+		}
+		details["free_bytes"] = free
+		details["total_bytes"] = total
+
+		if free < minFree {
+			return details, fmt.Errorf("%d bytes free on %s, need at least %d", free, path, minFree)
+		}
+		return details, nil
+	}
+}
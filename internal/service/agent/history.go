@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Shemistan/agent/internal/service"
+	"github.com/Shemistan/agent/internal/storage"
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 500
+)
+
+// ManagerCheckHistoryService implements reading of stored manager checks
+type ManagerCheckHistoryService struct {
+	historyStorage storage.ManagerCheckHistoryStorage
+	logger         *slog.Logger
+}
+
+// NewManagerCheckHistoryService creates a new ManagerCheckHistoryService instance
+func NewManagerCheckHistoryService(
+	historyStorage storage.ManagerCheckHistoryStorage,
+	logger *slog.Logger,
+) *ManagerCheckHistoryService {
+	return &ManagerCheckHistoryService{
+		historyStorage: historyStorage,
+		logger:         logger,
+	}
+}
+
+// ListManagerChecks returns a page of stored checks, newest first
+func (s *ManagerCheckHistoryService) ListManagerChecks(
+	ctx context.Context,
+	query service.ManagerCheckQuery,
+) (service.ManagerCheckPage, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	if limit > maxHistoryLimit {
+		limit = maxHistoryLimit
+	}
+
+	// Fetch one extra row to find out whether another page exists
+	checks, err := s.historyStorage.ListManagerChecks(ctx, storage.ManagerCheckFilter{
+		ManagerURL: query.ManagerURL,
+		Status:     string(query.Status),
+		From:       query.From,
+		To:         query.To,
+		Limit:      limit + 1,
+
+		BeforeCheckedAt: query.Cursor.CheckedAt,
+		BeforeID:        query.Cursor.ID,
+	})
+	if err != nil {
+		return service.ManagerCheckPage{}, err
+	}
+
+	page := service.ManagerCheckPage{
+		Checks: make([]service.ManagerCheckRecord, 0, min(len(checks), limit)),
+	}
+	for i, check := range checks {
+		if i == limit {
+			page.NextCursor = service.ManagerCheckCursor{CheckedAt: checks[limit-1].CheckedAt, ID: checks[limit-1].ID}
+			break
+		}
+		page.Checks = append(page.Checks, service.ManagerCheckRecord{
+			ID:           check.ID,
+			CheckedAt:    check.CheckedAt,
+			ManagerURL:   check.ManagerURL,
+			Status:       service.Status(check.Status),
+			HTTPStatus:   check.HTTPStatus,
+			ErrorMessage: check.ErrorMessage,
+			Timing: service.CheckTiming{
+				DNS:          fromMillis(check.DNSMillis),
+				Connect:      fromMillis(check.ConnectMillis),
+				TLSHandshake: fromMillis(check.TLSMillis),
+				FirstByte:    fromMillis(check.TTFBMillis),
+				Total:        fromMillis(check.TotalMillis),
+			},
+			Attempts: check.Attempts,
+		})
+	}
+
+	return page, nil
+}
+
+// fromMillis converts a stored millisecond value back to a duration, zero if absent
+func fromMillis(ms *float64) time.Duration {
+	if ms == nil {
+		return 0
+	}
+	return time.Duration(*ms * float64(time.Millisecond))
+}
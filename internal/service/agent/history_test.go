@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Shemistan/agent/internal/service"
+	"github.com/Shemistan/agent/internal/storage"
+)
+
+// MockHistoryStorage implements storage.ManagerCheckHistoryStorage interface
+type MockHistoryStorage struct {
+	checks     []storage.ManagerCheck // newest first
+	lastFilter storage.ManagerCheckFilter
+}
+
+func (m *MockHistoryStorage) ListManagerChecks(ctx context.Context, filter storage.ManagerCheckFilter) ([]storage.ManagerCheck, error) {
+	m.lastFilter = filter
+
+	var result []storage.ManagerCheck
+	for _, check := range m.checks {
+		if filter.BeforeID > 0 && !check.CheckedAt.Before(filter.BeforeCheckedAt) &&
+			(!check.CheckedAt.Equal(filter.BeforeCheckedAt) || check.ID >= filter.BeforeID) {
+			continue
+		}
+		if len(result) == filter.Limit {
+			break
+		}
+		result = append(result, check)
+	}
+	return result, nil
+}
+
+// historyChecks returns checks with ids from n down to 1, one minute apart, newest first
+func historyChecks(n int64) []storage.ManagerCheck {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var checks []storage.ManagerCheck
+	for id := n; id >= 1; id-- {
+		checks = append(checks, storage.ManagerCheck{
+			ID:         id,
+			CheckedAt:  start.Add(time.Duration(id) * time.Minute),
+			ManagerURL: "http://m1",
+			Status:     "success",
+		})
+	}
+	return checks
+}
+
+func TestManagerCheckHistoryService_Paginates(t *testing.T) {
+	mockStorage := &MockHistoryStorage{checks: historyChecks(5)}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	historyService := NewManagerCheckHistoryService(mockStorage, logger)
+
+	first, err := historyService.ListManagerChecks(context.Background(), serviceQuery(2, service.ManagerCheckCursor{}))
+	if err != nil {
+		t.Fatalf("ListManagerChecks failed: %v", err)
+	}
+
+	if len(first.Checks) != 2 || first.Checks[0].ID != 5 || first.Checks[1].ID != 4 {
+		t.Fatalf("Expected checks 5 and 4, got %+v", first.Checks)
+	}
+
+	if first.NextCursor.ID != 4 || !first.NextCursor.CheckedAt.Equal(first.Checks[1].CheckedAt) {
+		t.Fatalf("Expected next cursor at check 4, got %+v", first.NextCursor)
+	}
+
+	if mockStorage.lastFilter.Limit != 3 {
+		t.Fatalf("Expected storage limit 3 (page size + 1), got %d", mockStorage.lastFilter.Limit)
+	}
+
+	cursor := mockStorage.checks[3]
+	last, err := historyService.ListManagerChecks(context.Background(), serviceQuery(2, service.ManagerCheckCursor{CheckedAt: cursor.CheckedAt, ID: cursor.ID}))
+	if err != nil {
+		t.Fatalf("ListManagerChecks failed: %v", err)
+	}
+
+	if len(last.Checks) != 1 || last.Checks[0].ID != 1 {
+		t.Fatalf("Expected only check 1 on last page, got %+v", last.Checks)
+	}
+
+	if !last.NextCursor.IsZero() {
+		t.Fatalf("Expected no next cursor on last page, got %+v", last.NextCursor)
+	}
+}
+
+func TestManagerCheckHistoryService_PagesPastPurgedCursorRow(t *testing.T) {
+	mockStorage := &MockHistoryStorage{checks: historyChecks(5)}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	historyService := NewManagerCheckHistoryService(mockStorage, logger)
+
+	first, err := historyService.ListManagerChecks(context.Background(), serviceQuery(2, service.ManagerCheckCursor{}))
+	if err != nil {
+		t.Fatalf("ListManagerChecks failed: %v", err)
+	}
+
+	// Retention purges the check the cursor was taken from before the client asks for the next page
+	mockStorage.checks = append(mockStorage.checks[:1:1], mockStorage.checks[2:]...)
+
+	cursor, err := service.ParseManagerCheckCursor(first.NextCursor.String())
+	if err != nil {
+		t.Fatalf("ParseManagerCheckCursor failed: %v", err)
+	}
+	if cursor.ID != first.NextCursor.ID || !cursor.CheckedAt.Equal(first.NextCursor.CheckedAt) {
+		t.Fatalf("Expected the cursor to survive encoding, got %+v for %+v", cursor, first.NextCursor)
+	}
+
+	next, err := historyService.ListManagerChecks(context.Background(), serviceQuery(2, cursor))
+	if err != nil {
+		t.Fatalf("ListManagerChecks failed: %v", err)
+	}
+	if len(next.Checks) != 2 || next.Checks[0].ID != 3 || next.Checks[1].ID != 2 {
+		t.Fatalf("Expected checks 3 and 2 after the purged cursor row, got %+v", next.Checks)
+	}
+	if next.NextCursor.IsZero() {
+		t.Fatal("Expected a next cursor while older checks remain")
+	}
+}
+
+func TestManagerCheckHistoryService_CapsLimit(t *testing.T) {
+	mockStorage := &MockHistoryStorage{}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	historyService := NewManagerCheckHistoryService(mockStorage, logger)
+
+	if _, err := historyService.ListManagerChecks(context.Background(), serviceQuery(100000, service.ManagerCheckCursor{})); err != nil {
+		t.Fatalf("ListManagerChecks failed: %v", err)
+	}
+
+	if mockStorage.lastFilter.Limit != maxHistoryLimit+1 {
+		t.Fatalf("Expected limit capped at %d, got %d", maxHistoryLimit+1, mockStorage.lastFilter.Limit)
+	}
+}
+
+func serviceQuery(limit int, cursor service.ManagerCheckCursor) service.ManagerCheckQuery {
+	return service.ManagerCheckQuery{Limit: limit, Cursor: cursor}
+}
@@ -0,0 +1,186 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Shemistan/agent/internal/storage"
+)
+
+// PartitionPolicy configures maintenance of the monthly partitions of manager_checks
+type PartitionPolicy struct {
+	Interval    time.Duration
+	MonthsAhead int // partitions kept ready after the current month
+
+	// Partitions entirely older than the TTL are removed once rolled up; zero keeps them forever
+	TTL        time.Duration
+	DetachOnly bool // detach expired partitions instead of dropping them
+}
+
+// PartitionMaintainer keeps monthly partitions of manager_checks created ahead of time
+// and removes partitions whose rows are past their TTL and rolled up
+type PartitionMaintainer struct {
+	partitions storage.PartitionStorage
+	rollups    storage.RetentionStorage
+	policy     PartitionPolicy
+	logger     *slog.Logger
+	now        func() time.Time
+
+	mu       sync.Mutex
+	stopLoop context.CancelFunc
+	loopDone chan struct{}
+}
+
+// NewPartitionMaintainer creates a new PartitionMaintainer instance
+func NewPartitionMaintainer(
+	partitionStorage storage.PartitionStorage,
+	retentionStorage storage.RetentionStorage,
+	policy PartitionPolicy,
+	logger *slog.Logger,
+) *PartitionMaintainer {
+	if policy.Interval <= 0 {
+		policy.Interval = time.Hour
+	}
+	if policy.MonthsAhead < 0 {
+		policy.MonthsAhead = 0
+	}
+	return &PartitionMaintainer{
+		partitions: partitionStorage,
+		rollups:    retentionStorage,
+		policy:     policy,
+		logger:     logger,
+		now:        time.Now,
+	}
+}
+
+// Start runs maintenance immediately and then every interval in the background
+func (m *PartitionMaintainer) Start(ctx context.Context) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stopLoop != nil {
+		return
+	}
+
+	loopCtx, stopLoop := context.WithCancel(ctx)
+	m.stopLoop = stopLoop
+	m.loopDone = make(chan struct{})
+
+	go m.loop(loopCtx)
+
+	m.logger.Info("partitions: started",
+		slog.Duration("interval", m.policy.Interval),
+		slog.Int("months_ahead", m.policy.MonthsAhead),
+		slog.Duration("ttl", m.policy.TTL),
+	)
+}
+
+// Stop cancels an in-flight run and waits for the loop to exit
+func (m *PartitionMaintainer) Stop() {
+	m.mu.Lock()
+	stopLoop, loopDone := m.stopLoop, m.loopDone
+	m.mu.Unlock()
+
+	if stopLoop == nil {
+		return
+	}
+
+	stopLoop()
+	<-loopDone
+	m.logger.Info("partitions: stopped")
+}
+
+func (m *PartitionMaintainer) loop(ctx context.Context) {
+	defer close(m.loopDone)
+
+	ticker := time.NewTicker(m.policy.Interval)
+	defer ticker.Stop()
+
+	for {
+		m.Run(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Run creates missing partitions up to MonthsAhead after the current month and removes expired ones
+func (m *PartitionMaintainer) Run(ctx context.Context) {
+	partitions, err := m.partitions.ListPartitions(ctx)
+	if err != nil {
+		m.logger.Error("partitions: list failed", slog.String("error", err.Error()))
+		return
+	}
+	if len(partitions) == 0 {
+		m.logger.Warn("partitions: manager_checks is not partitioned, skipping")
+		return
+	}
+
+	now := m.now().UTC()
+	m.createAhead(ctx, partitions, now)
+	if m.policy.TTL > 0 {
+		m.removeExpired(ctx, partitions, now.Add(-m.policy.TTL))
+	}
+}
+
+// createAhead creates the partitions of the current and the next MonthsAhead months that do not exist yet
+func (m *PartitionMaintainer) createAhead(ctx context.Context, partitions []storage.Partition, now time.Time) {
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i <= m.policy.MonthsAhead; i++ {
+		from := month.AddDate(0, i, 0)
+		if partitionCovers(partitions, from) {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err := m.partitions.CreatePartition(ctx, from); err != nil {
+			m.logger.Error("partitions: create failed", slog.Time("from", from), slog.String("error", err.Error()))
+			return
+		}
+		m.logger.Info("partitions: created", slog.Time("from", from))
+	}
+}
+
+// removeExpired removes partitions that end before cutoff. Partitions with rows that are not
+// yet part of both rollups are kept regardless of their age.
+func (m *PartitionMaintainer) removeExpired(ctx context.Context, partitions []storage.Partition, cutoff time.Time) {
+	rolledUp, err := rolledUpTo(ctx, m.rollups, storage.RollupManagerChecks)
+	if err != nil {
+		m.logger.Error("partitions: get rollup watermark failed", slog.String("error", err.Error()))
+		return
+	}
+	if rolledUp.Before(cutoff) {
+		cutoff = rolledUp
+	}
+
+	for _, partition := range partitions {
+		if partition.To.After(cutoff) || ctx.Err() != nil {
+			continue
+		}
+		if err := m.partitions.RemovePartition(ctx, partition.Name, m.policy.DetachOnly); err != nil {
+			m.logger.Error("partitions: remove failed", slog.String("partition", partition.Name), slog.String("error", err.Error()))
+			continue
+		}
+		m.logger.Info("partitions: removed expired partition",
+			slog.String("partition", partition.Name),
+			slog.Time("to", partition.To),
+			slog.Bool("detached_only", m.policy.DetachOnly),
+		)
+	}
+}
+
+// partitionCovers reports whether the month starting at month falls into an existing partition
+func partitionCovers(partitions []storage.Partition, month time.Time) bool {
+	for _, partition := range partitions {
+		if !month.Before(partition.From) && month.Before(partition.To) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Shemistan/agent/internal/storage"
+)
+
+type removeCall struct {
+	name       string
+	detachOnly bool
+}
+
+// MockPartitionStorage implements storage.PartitionStorage interface
+type MockPartitionStorage struct {
+	partitions []storage.Partition
+	created    []time.Time
+	removed    []removeCall
+}
+
+func (m *MockPartitionStorage) ListPartitions(ctx context.Context) ([]storage.Partition, error) {
+	return m.partitions, nil
+}
+
+func (m *MockPartitionStorage) CreatePartition(ctx context.Context, from time.Time) error {
+	m.created = append(m.created, from)
+	return nil
+}
+
+func (m *MockPartitionStorage) RemovePartition(ctx context.Context, name string, detachOnly bool) error {
+	m.removed = append(m.removed, removeCall{name, detachOnly})
+	return nil
+}
+
+func month(year int, m time.Month) time.Time {
+	return time.Date(year, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func monthlyPartitions(from time.Time, months int) []storage.Partition {
+	partitions := []storage.Partition{{Name: "manager_checks_legacy", To: from}}
+	for i := 0; i < months; i++ {
+		start := from.AddDate(0, i, 0)
+		partitions = append(partitions, storage.Partition{
+			Name: "manager_checks_p" + start.Format("200601"),
+			From: start,
+			To:   start.AddDate(0, 1, 0),
+		})
+	}
+	return partitions
+}
+
+func TestPartitionMaintainer_CreatesMissingMonthsAhead(t *testing.T) {
+	mock := &MockPartitionStorage{partitions: monthlyPartitions(month(2025, 3), 1)}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	maintainer := NewPartitionMaintainer(mock, &MockRetentionStorage{}, PartitionPolicy{MonthsAhead: 2}, logger)
+	maintainer.now = func() time.Time { return time.Date(2025, 3, 20, 10, 0, 0, 0, time.UTC) }
+
+	maintainer.Run(context.Background())
+
+	expected := []time.Time{month(2025, 4), month(2025, 5)}
+	if len(mock.created) != len(expected) {
+		t.Fatalf("Expected partitions from %v, got %v", expected, mock.created)
+	}
+	for i, from := range expected {
+		if !mock.created[i].Equal(from) {
+			t.Errorf("Partition %d: expected %v, got %v", i, from, mock.created[i])
+		}
+	}
+	if len(mock.removed) != 0 {
+		t.Errorf("Expected no partitions removed without TTL, got %+v", mock.removed)
+	}
+}
+
+func TestPartitionMaintainer_CurrentMonthInLegacyPartition(t *testing.T) {
+	mock := &MockPartitionStorage{partitions: monthlyPartitions(month(2025, 4), 2)}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	maintainer := NewPartitionMaintainer(mock, &MockRetentionStorage{}, PartitionPolicy{MonthsAhead: 2}, logger)
+	maintainer.now = func() time.Time { return time.Date(2025, 3, 20, 10, 0, 0, 0, time.UTC) }
+
+	maintainer.Run(context.Background())
+
+	if len(mock.created) != 0 {
+		t.Errorf("Expected no partitions created, got %v", mock.created)
+	}
+}
+
+func TestPartitionMaintainer_RemovesOnlyRolledUpExpiredPartitions(t *testing.T) {
+	mock := &MockPartitionStorage{partitions: monthlyPartitions(month(2025, 1), 4)}
+	rollups := &MockRetentionStorage{watermarks: map[string]time.Time{
+		storage.RollupManagerChecks + "/" + storage.RollupHourly: time.Date(2025, 3, 20, 9, 0, 0, 0, time.UTC),
+		storage.RollupManagerChecks + "/" + storage.RollupDaily:  month(2025, 2),
+	}}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	maintainer := NewPartitionMaintainer(mock, rollups, PartitionPolicy{MonthsAhead: 1, TTL: 24 * time.Hour, DetachOnly: true}, logger)
+	maintainer.now = func() time.Time { return time.Date(2025, 3, 20, 10, 0, 0, 0, time.UTC) }
+
+	maintainer.Run(context.Background())
+
+	// The daily rollup has only reached February, so February stays despite the TTL
+	expected := []removeCall{{"manager_checks_legacy", true}, {"manager_checks_p202501", true}}
+	if len(mock.removed) != len(expected) {
+		t.Fatalf("Expected removed %+v, got %+v", expected, mock.removed)
+	}
+	for i, call := range expected {
+		if mock.removed[i] != call {
+			t.Errorf("Removal %d: expected %+v, got %+v", i, call, mock.removed[i])
+		}
+	}
+}
+
+func TestPartitionMaintainer_SkipsUnpartitionedTable(t *testing.T) {
+	mock := &MockPartitionStorage{}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	maintainer := NewPartitionMaintainer(mock, &MockRetentionStorage{}, PartitionPolicy{MonthsAhead: 2, TTL: time.Hour}, logger)
+
+	maintainer.Run(context.Background())
+
+	if len(mock.created) != 0 || len(mock.removed) != 0 {
+		t.Errorf("Expected no changes, got created %v, removed %+v", mock.created, mock.removed)
+	}
+}
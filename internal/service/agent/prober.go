@@ -0,0 +1,49 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+
+	"github.com/Shemistan/agent/internal/service"
+)
+
+// Prober checks a single target of one probe type. Besides the result it reports
+// whether a failure is transient and worth retrying. ManagerName, ManagerURL,
+// Attempts and the total duration are filled in by ManagerCheckService.
+type Prober interface {
+	Probe(ctx context.Context, target service.ManagerTarget) (service.ManagerCheckResult, bool)
+}
+
+// probeType returns the probe type of a target, HTTP unless set
+func probeType(target service.ManagerTarget) string {
+	if target.Type == "" {
+		return service.ProbeHTTP
+	}
+	return target.Type
+}
+
+// failed returns an error result with the given message
+func failed(errMsg string) service.ManagerCheckResult {
+	return service.ManagerCheckResult{Status: service.StatusError, ErrorMessage: errMsg}
+}
+
+// hostPort extracts "host:port" from a target URL such as "tcp://db:5432",
+// falling back to defaultPort when the URL has none
+func hostPort(rawURL, defaultPort string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if u.Hostname() == "" {
+		return "", fmt.Errorf("missing host in %s", rawURL)
+	}
+	if u.Port() != "" {
+		return u.Host, nil
+	}
+	if defaultPort == "" {
+		return "", fmt.Errorf("missing port in %s", rawURL)
+	}
+	return net.JoinHostPort(u.Hostname(), defaultPort), nil
+}
@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/url"
+
+	"github.com/Shemistan/agent/internal/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCProber checks targets with the standard grpc.health.v1 health checking protocol
+type GRPCProber struct {
+	tlsConfig *tls.Config
+}
+
+// NewGRPCProber creates a new GRPCProber instance; tlsConfig is used for grpcs:// targets,
+// its CA and client certificate only for targets with ManagerTLS
+func NewGRPCProber(tlsConfig *tls.Config) *GRPCProber {
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return &GRPCProber{tlsConfig: tlsConfig}
+}
+
+// Probe implements Prober interface. The target URL is "grpc://host:port" for plaintext
+// or "grpcs://host:port" for TLS; GRPCService selects the service to check.
+func (p *GRPCProber) Probe(ctx context.Context, target service.ManagerTarget) (service.ManagerCheckResult, bool) {
+	u, err := url.Parse(target.URL)
+	if err != nil {
+		return failed(fmt.Sprintf("invalid address: %v", err)), false
+	}
+
+	creds := insecure.NewCredentials()
+	defaultPort := "80"
+	if u.Scheme == "grpcs" {
+		creds = credentials.NewTLS(targetTLSConfig(p.tlsConfig, target, u.Hostname()))
+		defaultPort = "443"
+	}
+	addr, err := hostPort(target.URL, defaultPort)
+	if err != nil {
+		return failed(fmt.Sprintf("invalid address: %v", err)), false
+	}
+
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return failed(fmt.Sprintf("failed to create gRPC client: %v", err)), false
+	}
+	defer conn.Close()
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: target.GRPCService})
+	if err != nil {
+		code := status.Code(err)
+		transient := code == codes.Unavailable || code == codes.DeadlineExceeded
+		return failed(fmt.Sprintf("gRPC health check failed: %v", err)), transient
+	}
+
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return failed(fmt.Sprintf("gRPC health status: %s", resp.GetStatus())), false
+	}
+	return service.ManagerCheckResult{Status: service.StatusSuccess}, false
+}
@@ -0,0 +1,217 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptrace"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Shemistan/agent/internal/service"
+)
+
+// defaultMaxBodyBytes limits how much of a response body is read for assertions
+const defaultMaxBodyBytes = 1 << 20
+
+// HTTPProber checks targets with an HTTP request and validates the status code,
+// response latency, headers and JSON body assertions
+type HTTPProber struct {
+	client        *http.Client
+	managerClient *http.Client // targets with ManagerTLS
+	logger        *slog.Logger
+}
+
+// NewHTTPProber creates a new HTTPProber instance. Targets are requested with client,
+// except targets with ManagerTLS, which use a copy of its transport with the manager TLS config.
+// A nil config uses client for all targets.
+func NewHTTPProber(client *http.Client, config *tls.Config, logger *slog.Logger) *HTTPProber {
+	return &HTTPProber{
+		client:        client,
+		managerClient: managerHTTPClient(client, config),
+		logger:        logger,
+	}
+}
+
+// managerHTTPClient returns a copy of client whose transport trusts the manager CA and presents
+// the manager client certificate. The transport is shared by all targets with ManagerTLS.
+func managerHTTPClient(client *http.Client, config *tls.Config) *http.Client {
+	if config == nil {
+		return client
+	}
+	base, ok := client.Transport.(*http.Transport)
+	if client.Transport == nil {
+		base, ok = http.DefaultTransport.(*http.Transport)
+	}
+	if !ok {
+		return client
+	}
+	transport := base.Clone()
+	transport.TLSClientConfig = targetTLSConfig(config, service.ManagerTarget{ManagerTLS: true}, "")
+	managerClient := *client
+	managerClient.Transport = transport
+	return &managerClient
+}
+
+// Probe implements Prober interface by sending the HTTP health request.
+// Phase timings are recorded via httptrace.
+func (p *HTTPProber) Probe(ctx context.Context, target service.ManagerTarget) (result service.ManagerCheckResult, transient bool) {
+	startedAt := time.Now()
+	timer := newTraceTimer()
+	ctx = httptrace.WithClientTrace(ctx, timer.trace())
+	defer func() {
+		result.Timing = timer.timing(time.Now())
+	}()
+
+	managerURL := target.URL
+	result = service.ManagerCheckResult{Status: service.StatusError}
+
+	method := target.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	path := target.HealthPath
+	if path == "" {
+		path = "/health"
+	}
+
+	var body io.Reader
+	if target.Body != "" {
+		body = strings.NewReader(target.Body)
+	}
+
+	url := managerURL + path
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to create request: %v", err)
+		result.ErrorMessage = errMsg
+		p.logger.Error("manager check: request creation failed", slog.String("url", managerURL), slog.String("error", errMsg))
+		return result, false
+	}
+	for name, value := range target.Headers {
+		if strings.EqualFold(name, "Host") {
+			req.Host = value
+			continue
+		}
+		req.Header.Set(name, value)
+	}
+
+	client := p.client
+	if target.ManagerTLS {
+		client = p.managerClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		errMsg := fmt.Sprintf("HTTP request failed: %v", err)
+		result.ErrorMessage = errMsg
+		p.logger.Error("manager check: HTTP request failed", slog.String("url", managerURL), slog.String("error", errMsg))
+		return result, isTransientError(err)
+	}
+	defer func() {
+		if cerr := resp.Body.Close(); cerr != nil {
+			p.logger.Warn("manager check: failed to close response body", slog.String("url", managerURL), slog.String("error", cerr.Error()))
+		}
+	}()
+
+	result.HTTPStatus = resp.StatusCode
+	if resp.TLS != nil {
+		result.TLSVersion = tls.VersionName(resp.TLS.Version)
+		if len(resp.TLS.PeerCertificates) > 0 {
+			result.CertNotAfter = resp.TLS.PeerCertificates[0].NotAfter
+		}
+	}
+
+	// Check HTTP status code
+	if !expectedStatus(target, resp.StatusCode) {
+		errMsg := fmt.Sprintf("unexpected HTTP status: %d", resp.StatusCode)
+		result.ErrorMessage = errMsg
+		p.logger.Error("manager check: unexpected status", slog.String("url", managerURL), slog.Int("status", resp.StatusCode))
+		return result, isTransientStatus(resp.StatusCode)
+	}
+
+	assertions := target.Assertions
+	if target.ExpectedField != "" {
+		// The legacy expectation, e.g. "status" == "success", is an equals assertion
+		assertions = append([]service.Assertion{{Path: target.ExpectedField, Value: target.ExpectedValue}}, assertions...)
+	}
+
+	var doc any
+	if hasBodyAssertions(assertions) || target.MaxBodyBytes > 0 {
+		// Read at most one byte over the limit to detect oversized responses
+		limit := target.MaxBodyBytes
+		if limit <= 0 {
+			limit = defaultMaxBodyBytes
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+		if err != nil {
+			errMsg := fmt.Sprintf("failed to read response body: %v", err)
+			result.ErrorMessage = errMsg
+			p.logger.Error("manager check: failed to read body", slog.String("url", managerURL), slog.String("error", errMsg))
+			return result, isTransientError(err)
+		}
+		if int64(len(body)) > limit {
+			errMsg := fmt.Sprintf("response body exceeds %d bytes", limit)
+			result.ErrorMessage = errMsg
+			p.logger.Error("manager check: response body too large", slog.String("url", managerURL), slog.Int64("limit", limit))
+			return result, false
+		}
+
+		if hasBodyAssertions(assertions) {
+			decoder := json.NewDecoder(bytes.NewReader(body))
+			decoder.UseNumber()
+			if err := decoder.Decode(&doc); err != nil {
+				errMsg := fmt.Sprintf("failed to parse response: %v", err)
+				result.ErrorMessage = errMsg
+				p.logger.Error("manager check: failed to parse response", slog.String("url", managerURL), slog.String("error", errMsg))
+				return result, false
+			}
+		}
+	}
+
+	latency := time.Since(startedAt)
+	if target.MaxLatency > 0 && latency > target.MaxLatency {
+		errMsg := fmt.Sprintf("latency %s exceeds %s", latency.Round(time.Millisecond), target.MaxLatency)
+		result.ErrorMessage = errMsg
+		p.logger.Error("manager check: too slow", slog.String("url", managerURL), slog.Duration("latency", latency))
+		return result, false
+	}
+
+	if failures := checkAssertions(assertions, resp.Header, doc); len(failures) > 0 {
+		result.Status = assertionStatus(failures)
+		result.FailedAssertions = failures
+		result.ErrorMessage = assertionError(failures)
+		p.logger.Error("manager check: assertions failed",
+			slog.String("url", managerURL),
+			slog.String("status", string(result.Status)),
+			slog.String("error", result.ErrorMessage),
+		)
+		return result, false
+	}
+
+	if target.DegradedLatency > 0 && latency > target.DegradedLatency {
+		result.Status = service.StatusDegraded
+		result.ErrorMessage = fmt.Sprintf("latency %s exceeds %s", latency.Round(time.Millisecond), target.DegradedLatency)
+		p.logger.Warn("manager check: degraded", slog.String("url", managerURL), slog.Duration("latency", latency))
+		return result, false
+	}
+
+	// Success case
+	result.Status = service.StatusSuccess
+	result.ErrorMessage = ""
+	p.logger.Info("manager check: success", slog.String("url", managerURL))
+	return result, false
+}
+
+// expectedStatus reports whether code is accepted for the target, 200 by default
+func expectedStatus(target service.ManagerTarget, code int) bool {
+	if len(target.ExpectedStatus) == 0 {
+		return code == http.StatusOK
+	}
+	return slices.Contains(target.ExpectedStatus, code)
+}
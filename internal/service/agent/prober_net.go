@@ -0,0 +1,165 @@
+package agent
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/Shemistan/agent/internal/service"
+)
+
+// TCPProber checks that a TCP connection to the target can be established
+type TCPProber struct {
+	dialer net.Dialer
+}
+
+// NewTCPProber creates a new TCPProber instance
+func NewTCPProber() *TCPProber {
+	return &TCPProber{}
+}
+
+// Probe implements Prober interface. The target URL is "tcp://host:port".
+func (p *TCPProber) Probe(ctx context.Context, target service.ManagerTarget) (service.ManagerCheckResult, bool) {
+	addr, err := hostPort(target.URL, "")
+	if err != nil {
+		return failed(fmt.Sprintf("invalid address: %v", err)), false
+	}
+
+	startedAt := time.Now()
+	conn, err := p.dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return failed(fmt.Sprintf("TCP connect failed: %v", err)), isTransientError(err)
+	}
+	_ = conn.Close()
+
+	return service.ManagerCheckResult{
+		Status: service.StatusSuccess,
+		Timing: service.CheckTiming{Connect: time.Since(startedAt)},
+	}, false
+}
+
+// TLSProber checks that a TLS handshake succeeds with a verified certificate
+// that is not about to expire
+type TLSProber struct {
+	config *tls.Config
+}
+
+// NewTLSProber creates a new TLSProber instance. The CA and client certificate of config are only
+// used for targets with ManagerTLS; a nil config uses system roots.
+func NewTLSProber(config *tls.Config) *TLSProber {
+	if config == nil {
+		config = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return &TLSProber{config: config}
+}
+
+// targetTLSConfig derives the client TLS configuration of a single target from the manager-wide one.
+// Targets verify the certificate of their own host and use system roots without a client certificate
+// unless they opt in to the manager CA, certificate and server name with ManagerTLS.
+// An empty host leaves the server name to the caller, e.g. an HTTP transport.
+func targetTLSConfig(config *tls.Config, target service.ManagerTarget, host string) *tls.Config {
+	config = config.Clone()
+	if !target.ManagerTLS {
+		config.RootCAs = nil
+		config.Certificates = nil
+		config.GetClientCertificate = nil
+		config.ServerName = ""
+	}
+	if config.ServerName == "" {
+		config.ServerName = host
+	}
+	return config
+}
+
+// Probe implements Prober interface. The target URL is "tls://host:port", port 443 by default.
+func (p *TLSProber) Probe(ctx context.Context, target service.ManagerTarget) (service.ManagerCheckResult, bool) {
+	addr, err := hostPort(target.URL, "443")
+	if err != nil {
+		return failed(fmt.Sprintf("invalid address: %v", err)), false
+	}
+
+	host, _, _ := net.SplitHostPort(addr)
+	config := targetTLSConfig(p.config, target, host)
+
+	startedAt := time.Now()
+	var dialer net.Dialer
+	rawConn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return failed(fmt.Sprintf("TCP connect failed: %v", err)), isTransientError(err)
+	}
+	defer rawConn.Close()
+	connected := time.Now()
+
+	conn := tls.Client(rawConn, config)
+	if err := conn.HandshakeContext(ctx); err != nil {
+		result := failed(fmt.Sprintf("TLS handshake failed: %v", err))
+		result.Timing.Connect = connected.Sub(startedAt)
+		return result, isTransientError(err)
+	}
+
+	state := conn.ConnectionState()
+	result := service.ManagerCheckResult{
+		Status:     service.StatusSuccess,
+		TLSVersion: tls.VersionName(state.Version),
+		Timing: service.CheckTiming{
+			Connect:      connected.Sub(startedAt),
+			TLSHandshake: time.Since(connected),
+		},
+	}
+	if len(state.PeerCertificates) == 0 {
+		result.Status = service.StatusError
+		result.ErrorMessage = "no peer certificate"
+		return result, false
+	}
+
+	result.CertNotAfter = state.PeerCertificates[0].NotAfter
+	if left := time.Until(result.CertNotAfter); left < target.MinCertValidity {
+		result.Status = service.StatusError
+		result.ErrorMessage = fmt.Sprintf("certificate expires in %s, at %s",
+			left.Round(time.Hour), result.CertNotAfter.Format(time.RFC3339))
+	}
+	return result, false
+}
+
+// DNSProber checks that a host name resolves, optionally to an expected address
+type DNSProber struct {
+	resolver *net.Resolver
+}
+
+// NewDNSProber creates a new DNSProber instance
+func NewDNSProber(resolver *net.Resolver) *DNSProber {
+	return &DNSProber{resolver: resolver}
+}
+
+// Probe implements Prober interface. The target URL is "dns://host";
+// ExpectedValue, if set, must be among the resolved addresses.
+func (p *DNSProber) Probe(ctx context.Context, target service.ManagerTarget) (service.ManagerCheckResult, bool) {
+	u, err := url.Parse(target.URL)
+	if err != nil || u.Hostname() == "" {
+		return failed(fmt.Sprintf("invalid host name in %s", target.URL)), false
+	}
+
+	startedAt := time.Now()
+	addrs, err := p.resolver.LookupHost(ctx, u.Hostname())
+	timing := service.CheckTiming{DNS: time.Since(startedAt)}
+	if err != nil {
+		result := failed(fmt.Sprintf("DNS lookup failed: %v", err))
+		result.Timing = timing
+
+		var dnsErr *net.DNSError
+		transient := errors.As(err, &dnsErr) && (dnsErr.IsTemporary || dnsErr.IsTimeout)
+		return result, transient
+	}
+
+	result := service.ManagerCheckResult{Status: service.StatusSuccess, Timing: timing}
+	if target.ExpectedValue != "" && !slices.Contains(addrs, target.ExpectedValue) {
+		result.Status = service.StatusError
+		result.ErrorMessage = fmt.Sprintf("%s resolved to %v, expected %s", u.Hostname(), addrs, target.ExpectedValue)
+	}
+	return result, false
+}
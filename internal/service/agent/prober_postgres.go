@@ -0,0 +1,34 @@
+package agent
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/Shemistan/agent/internal/service"
+)
+
+// PostgresProber checks that a PostgreSQL database accepts connections.
+// It relies on the "postgres" driver being registered by the application.
+type PostgresProber struct{}
+
+// NewPostgresProber creates a new PostgresProber instance
+func NewPostgresProber() *PostgresProber {
+	return &PostgresProber{}
+}
+
+// Probe implements Prober interface. The target URL is a postgres:// connection URL;
+// keep the password out of it, the driver reads PGPASSWORD or ~/.pgpass instead.
+func (p *PostgresProber) Probe(ctx context.Context, target service.ManagerTarget) (service.ManagerCheckResult, bool) {
+	db, err := sql.Open("postgres", target.URL)
+	if err != nil {
+		return failed(fmt.Sprintf("failed to open database: %v", err)), false
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if err := db.PingContext(ctx); err != nil {
+		return failed(fmt.Sprintf("database ping failed: %v", err)), isTransientError(err)
+	}
+	return service.ManagerCheckResult{Status: service.StatusSuccess}, false
+}
@@ -0,0 +1,170 @@
+package agent
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Shemistan/agent/internal/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestTCPProber(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	addr := listener.Addr().String()
+
+	prober := NewTCPProber()
+	result, _ := prober.Probe(context.Background(), service.ManagerTarget{URL: "tcp://" + addr})
+	if result.Status != "success" {
+		t.Fatalf("Expected success, got %s (%s)", result.Status, result.ErrorMessage)
+	}
+
+	if err := listener.Close(); err != nil {
+		t.Fatalf("failed to close listener: %v", err)
+	}
+	result, transient := prober.Probe(context.Background(), service.ManagerTarget{URL: "tcp://" + addr})
+	if result.Status != "error" || !transient {
+		t.Fatalf("Expected transient error for refused connection, got %s (transient=%v)", result.Status, transient)
+	}
+}
+
+func TestTLSProber(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	// The test certificate is issued for example.com, so the manager server name must be kept
+	prober := NewTLSProber(&tls.Config{RootCAs: pool, ServerName: "example.com", MinVersion: tls.VersionTLS12})
+	target := service.ManagerTarget{URL: strings.Replace(server.URL, "https://", "tls://", 1), ManagerTLS: true}
+
+	result, _ := prober.Probe(context.Background(), target)
+	if result.Status != "success" {
+		t.Fatalf("Expected success, got %s (%s)", result.Status, result.ErrorMessage)
+	}
+	if result.TLSVersion == "" || result.CertNotAfter.IsZero() {
+		t.Fatalf("Expected TLS details, got %+v", result)
+	}
+
+	// The test certificate is valid for far less than a hundred years
+	target.MinCertValidity = 100 * 365 * 24 * time.Hour
+	result, _ = prober.Probe(context.Background(), target)
+	if result.Status != "error" || !strings.Contains(result.ErrorMessage, "certificate expires") {
+		t.Fatalf("Expected certificate expiry error, got %s (%s)", result.Status, result.ErrorMessage)
+	}
+
+	// Without opting in the manager CA is not trusted
+	result, transient := prober.Probe(context.Background(), service.ManagerTarget{URL: target.URL})
+	if result.Status != "error" || transient {
+		t.Fatalf("Expected permanent error for untrusted certificate, got %s (transient=%v)", result.Status, transient)
+	}
+}
+
+func TestTargetTLSConfig(t *testing.T) {
+	manager := &tls.Config{
+		ServerName:   "manager.internal",
+		RootCAs:      x509.NewCertPool(),
+		Certificates: []tls.Certificate{{}},
+		MinVersion:   tls.VersionTLS13,
+	}
+
+	config := targetTLSConfig(manager, service.ManagerTarget{}, "db.internal")
+	if config.ServerName != "db.internal" || config.RootCAs != nil || config.Certificates != nil {
+		t.Fatalf("Expected only the target host and system roots, got %+v", config)
+	}
+	if config.MinVersion != tls.VersionTLS13 {
+		t.Errorf("Expected the manager-wide minimum version to be kept, got %x", config.MinVersion)
+	}
+
+	config = targetTLSConfig(manager, service.ManagerTarget{ManagerTLS: true}, "185.211.170.173")
+	if config.ServerName != "manager.internal" || config.RootCAs == nil || len(config.Certificates) != 1 {
+		t.Fatalf("Expected the manager CA, certificate and server name for an opted-in target, got %+v", config)
+	}
+
+	manager.ServerName = ""
+	config = targetTLSConfig(manager, service.ManagerTarget{ManagerTLS: true}, "manager-1.internal")
+	if config.ServerName != "manager-1.internal" {
+		t.Fatalf("Expected the target host without a manager server name, got %q", config.ServerName)
+	}
+	manager.ServerName = "manager.internal"
+	if manager.ServerName != "manager.internal" || len(manager.Certificates) != 1 {
+		t.Error("Expected the manager-wide config to stay untouched")
+	}
+}
+
+func TestDNSProber(t *testing.T) {
+	prober := NewDNSProber(net.DefaultResolver)
+
+	result, _ := prober.Probe(context.Background(), service.ManagerTarget{URL: "dns://localhost", ExpectedValue: "127.0.0.1"})
+	if result.Status != "success" {
+		t.Fatalf("Expected localhost to resolve, got %s (%s)", result.Status, result.ErrorMessage)
+	}
+
+	result, _ = prober.Probe(context.Background(), service.ManagerTarget{URL: "dns://localhost", ExpectedValue: "192.0.2.1"})
+	if result.Status != "error" {
+		t.Fatal("Expected error for unexpected address")
+	}
+}
+
+func TestGRPCProber(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("agent.Manager", healthpb.HealthCheckResponse_NOT_SERVING)
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+
+	prober := NewGRPCProber(nil)
+	url := "grpc://" + listener.Addr().String()
+
+	result, _ := prober.Probe(context.Background(), service.ManagerTarget{URL: url})
+	if result.Status != "success" {
+		t.Fatalf("Expected serving server, got %s (%s)", result.Status, result.ErrorMessage)
+	}
+
+	result, _ = prober.Probe(context.Background(), service.ManagerTarget{URL: url, GRPCService: "agent.Manager"})
+	if result.Status != "error" || !strings.Contains(result.ErrorMessage, "NOT_SERVING") {
+		t.Fatalf("Expected NOT_SERVING error, got %s (%s)", result.Status, result.ErrorMessage)
+	}
+}
+
+func TestManagerCheckService_CheckManager_UnknownProbeType(t *testing.T) {
+	mockStorage := &MockManagerCheckStorage{}
+	checkService := NewManagerCheckService(
+		http.DefaultClient,
+		mockStorage,
+		[]service.ManagerTarget{{Name: "ftp", Type: "ftp", URL: "ftp://files"}},
+		slog.New(slog.NewTextHandler(os.Stdout, nil)),
+	)
+
+	results, err := checkService.CheckManager(context.Background())
+	if err != nil {
+		t.Fatalf("CheckManager failed: %v", err)
+	}
+
+	result := results.Results[0]
+	if result.Status != "error" || result.ManagerURL != "ftp://files" {
+		t.Fatalf("Expected error result for unknown probe type, got %+v", result)
+	}
+	if len(mockStorage.savedChecks) != 1 {
+		t.Fatalf("Expected the failed check to be saved, got %d", len(mockStorage.savedChecks))
+	}
+}
@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Shemistan/agent/internal/service"
+)
+
+// HealthCheckFunc checks a single dependency of the agent.
+// Details are reported in verbose probe output whether or not the check fails.
+type HealthCheckFunc func(ctx context.Context) (details map[string]any, err error)
+
+// HealthCheckKind tells which probes a check belongs to
+type HealthCheckKind int
+
+// Health check kinds
+const (
+	LivenessCheck  HealthCheckKind = iota // failing means the process should be restarted; also part of readiness
+	ReadinessCheck                        // failing means the agent should not receive traffic
+)
+
+// defaultHealthCheckTimeout bounds a single check when the registry has no timeout configured
+const defaultHealthCheckTimeout = 2 * time.Second
+
+// namedCheck is a registered health check
+type namedCheck struct {
+	name  string
+	kind  HealthCheckKind
+	check HealthCheckFunc
+}
+
+// HealthRegistry implements ProbeService interface on top of named health checks
+type HealthRegistry struct {
+	timeout time.Duration
+	logger  *slog.Logger
+
+	mu     sync.RWMutex
+	checks []namedCheck
+}
+
+// NewHealthRegistry creates a new HealthRegistry instance; each check runs under timeout
+func NewHealthRegistry(timeout time.Duration, logger *slog.Logger) *HealthRegistry {
+	if timeout <= 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+	return &HealthRegistry{
+		timeout: timeout,
+		logger:  logger,
+	}
+}
+
+// Register adds a named check; results are reported in registration order
+func (r *HealthRegistry) Register(name string, kind HealthCheckKind, check HealthCheckFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, namedCheck{name: name, kind: kind, check: check})
+}
+
+// Liveness runs the liveness checks
+func (r *HealthRegistry) Liveness(ctx context.Context) service.HealthReport {
+	return r.run(ctx, LivenessCheck)
+}
+
+// Readiness runs the readiness and liveness checks
+func (r *HealthRegistry) Readiness(ctx context.Context) service.HealthReport {
+	return r.run(ctx, ReadinessCheck)
+}
+
+// run executes all checks up to kind concurrently
+func (r *HealthRegistry) run(ctx context.Context, kind HealthCheckKind) service.HealthReport {
+	r.mu.RLock()
+	checks := make([]namedCheck, 0, len(r.checks))
+	for _, c := range r.checks {
+		if c.kind <= kind {
+			checks = append(checks, c)
+		}
+	}
+	r.mu.RUnlock()
+
+	report := service.HealthReport{
+		Status: service.StatusSuccess,
+		Checks: make([]service.HealthCheckResult, len(checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = r.runCheck(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != service.StatusSuccess {
+			report.Status = service.StatusError
+		}
+	}
+	return report
+}
+
+// runCheck executes a single check under the registry timeout
+func (r *HealthRegistry) runCheck(ctx context.Context, c namedCheck) service.HealthCheckResult {
+	checkCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	startedAt := time.Now()
+	details, err := c.check(checkCtx)
+	result := service.HealthCheckResult{
+		Name:     c.name,
+		Status:   service.StatusSuccess,
+		Duration: time.Since(startedAt),
+		Details:  details,
+	}
+	if err != nil {
+		result.Status = service.StatusError
+		result.Error = err.Error()
+		r.logger.Warn("health check failed", slog.String("check", c.name), slog.String("error", result.Error))
+	}
+	return result
+}
@@ -0,0 +1,120 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Shemistan/agent/internal/service"
+)
+
+// MockSchemaStorage implements storage.SchemaStorage interface
+type MockSchemaStorage struct {
+	pingErr error
+	version int64
+}
+
+func (m *MockSchemaStorage) Ping(ctx context.Context) error {
+	return m.pingErr
+}
+
+func (m *MockSchemaStorage) SchemaVersion(ctx context.Context) (int64, error) {
+	return m.version, m.pingErr
+}
+
+// MockScheduler implements service.Scheduler interface
+type MockScheduler struct {
+	status service.SchedulerStatus
+}
+
+func (m *MockScheduler) Status() service.SchedulerStatus {
+	return m.status
+}
+
+func TestHealthRegistry_LivenessAndReadiness(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	registry := NewHealthRegistry(50*time.Millisecond, logger)
+
+	registry.Register("loop", LivenessCheck, func(context.Context) (map[string]any, error) {
+		return map[string]any{"ok": true}, nil
+	})
+	registry.Register("database", ReadinessCheck, DatabaseCheck(&MockSchemaStorage{pingErr: errors.New("pool exhausted")}))
+	registry.Register("slow", ReadinessCheck, func(ctx context.Context) (map[string]any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	live := registry.Liveness(context.Background())
+	if live.Status != service.StatusSuccess || len(live.Checks) != 1 || live.Checks[0].Name != "loop" {
+		t.Fatalf("Expected only the passing liveness check, got %+v", live)
+	}
+
+	ready := registry.Readiness(context.Background())
+	if ready.Status != service.StatusError || len(ready.Checks) != 3 {
+		t.Fatalf("Expected failed readiness with all checks, got %+v", ready)
+	}
+	if ready.Checks[1].Error != "pool exhausted" {
+		t.Fatalf("Expected database error, got %+v", ready.Checks[1])
+	}
+	if ready.Checks[2].Status != service.StatusError {
+		t.Fatalf("Expected the slow check to time out, got %+v", ready.Checks[2])
+	}
+}
+
+func TestMigrationCheck(t *testing.T) {
+	tests := []struct {
+		current int64
+		fails   bool
+	}{
+		{current: 7, fails: true},
+		{current: 8},
+		{current: 9}, // newer schema during a rolling update
+	}
+	for _, tt := range tests {
+		details, err := MigrationCheck(&MockSchemaStorage{version: tt.current}, 8)(context.Background())
+		if (err != nil) != tt.fails {
+			t.Errorf("schema %d: expected failure %v, got %v", tt.current, tt.fails, err)
+		}
+		if details["current_version"] != tt.current || details["expected_version"] != int64(8) {
+			t.Errorf("schema %d: unexpected details %v", tt.current, details)
+		}
+	}
+}
+
+func TestSchedulerCheck(t *testing.T) {
+	since := time.Now().Add(-time.Minute)
+
+	disabled := &MockScheduler{}
+	if _, err := SchedulerCheck(disabled, time.Second, since)(context.Background()); err != nil {
+		t.Fatalf("Expected disabled scheduler to pass, got %v", err)
+	}
+
+	neverRan := &MockScheduler{status: service.SchedulerStatus{Enabled: true}}
+	if _, err := SchedulerCheck(neverRan, 2*time.Minute, since)(context.Background()); err != nil {
+		t.Fatalf("Expected grace period before the first run, got %v", err)
+	}
+
+	stuck := &MockScheduler{status: service.SchedulerStatus{Enabled: true, LastRunAt: time.Now().Add(-10 * time.Minute)}}
+	if _, err := SchedulerCheck(stuck, 2*time.Minute, since)(context.Background()); err == nil {
+		t.Fatal("Expected stuck scheduler to fail")
+	}
+}
+
+func TestDiskCheck(t *testing.T) {
+	dir := t.TempDir()
+
+	details, err := DiskCheck(dir, 1)(context.Background())
+	if err != nil {
+		t.Fatalf("Expected free space on %s, got %v", dir, err)
+	}
+	if details["free_bytes"] == nil {
+		t.Fatalf("Expected free bytes in details, got %v", details)
+	}
+
+	if _, err := DiskCheck(dir, 1<<62)(context.Background()); err == nil {
+		t.Fatal("Expected failure when requiring more space than available")
+	}
+}
@@ -0,0 +1,221 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Shemistan/agent/internal/storage"
+)
+
+// retentionBatchPause separates delete batches so that other writers get the table in between
+const retentionBatchPause = 50 * time.Millisecond
+
+// RetentionPolicy configures downsampling and purging of raw history
+type RetentionPolicy struct {
+	Interval  time.Duration
+	BatchSize int // rows deleted per statement
+
+	// Raw rows older than the TTL are purged once rolled up; zero keeps them forever
+	HealthCallsTTL   time.Duration
+	ManagerChecksTTL time.Duration
+
+	// Buckets are rolled up only once they ended at least this long ago, so that rows written late,
+	// e.g. replayed from the outbox, are still counted; a bucket is never rolled up twice
+	RollupLag time.Duration
+}
+
+// RetentionJob periodically rolls raw health_calls and manager_checks up into hourly and daily
+// aggregates and purges raw rows past their TTL in batches
+type RetentionJob struct {
+	storage storage.RetentionStorage
+	policy  RetentionPolicy
+	logger  *slog.Logger
+	now     func() time.Time
+
+	mu       sync.Mutex
+	stopLoop context.CancelFunc
+	loopDone chan struct{}
+}
+
+// NewRetentionJob creates a new RetentionJob instance
+func NewRetentionJob(retentionStorage storage.RetentionStorage, policy RetentionPolicy, logger *slog.Logger) *RetentionJob {
+	if policy.BatchSize <= 0 {
+		policy.BatchSize = 5000
+	}
+	if policy.Interval <= 0 {
+		policy.Interval = time.Hour
+	}
+	return &RetentionJob{
+		storage: retentionStorage,
+		policy:  policy,
+		logger:  logger,
+		now:     time.Now,
+	}
+}
+
+// Start runs retention immediately and then every interval in the background
+func (j *RetentionJob) Start(ctx context.Context) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.stopLoop != nil {
+		return
+	}
+
+	loopCtx, stopLoop := context.WithCancel(ctx)
+	j.stopLoop = stopLoop
+	j.loopDone = make(chan struct{})
+
+	go j.loop(loopCtx)
+
+	j.logger.Info("retention: started",
+		slog.Duration("interval", j.policy.Interval),
+		slog.Duration("health_calls_ttl", j.policy.HealthCallsTTL),
+		slog.Duration("manager_checks_ttl", j.policy.ManagerChecksTTL),
+		slog.Duration("rollup_lag", j.policy.RollupLag),
+	)
+}
+
+// Stop cancels an in-flight run and waits for the loop to exit.
+// Rollups are transactional and purging resumes on the next start, so nothing is lost.
+func (j *RetentionJob) Stop() {
+	j.mu.Lock()
+	stopLoop, loopDone := j.stopLoop, j.loopDone
+	j.mu.Unlock()
+
+	if stopLoop == nil {
+		return
+	}
+
+	stopLoop()
+	<-loopDone
+	j.logger.Info("retention: stopped")
+}
+
+func (j *RetentionJob) loop(ctx context.Context) {
+	defer close(j.loopDone)
+
+	ticker := time.NewTicker(j.policy.Interval)
+	defer ticker.Stop()
+
+	for {
+		j.Run(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Run rolls up all complete buckets and then purges expired raw rows of every source
+func (j *RetentionJob) Run(ctx context.Context) {
+	now := j.now().UTC()
+	sources := []struct {
+		name string
+		ttl  time.Duration
+	}{
+		{storage.RollupHealthCalls, j.policy.HealthCallsTTL},
+		{storage.RollupManagerChecks, j.policy.ManagerChecksTTL},
+	}
+
+	for _, source := range sources {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := j.rollup(ctx, source.name, now); err != nil {
+			j.logger.Error("retention: rollup failed", slog.String("source", source.name), slog.String("error", err.Error()))
+			continue
+		}
+		if source.ttl <= 0 {
+			continue
+		}
+		if err := j.purge(ctx, source.name, now.Add(-source.ttl)); err != nil {
+			j.logger.Error("retention: purge failed", slog.String("source", source.name), slog.String("error", err.Error()))
+		}
+	}
+}
+
+// rollup aggregates the hours and days since the last rollup of source that ended at least RollupLag ago
+func (j *RetentionJob) rollup(ctx context.Context, source string, now time.Time) error {
+	for _, resolution := range []string{storage.RollupHourly, storage.RollupDaily} {
+		from, err := j.storage.RollupWatermark(ctx, source, resolution)
+		if err != nil {
+			return err
+		}
+		to := bucketStart(now.Add(-j.policy.RollupLag), resolution)
+		if !from.Before(to) {
+			continue
+		}
+
+		if err := j.storage.Rollup(ctx, source, resolution, from, to); err != nil {
+			return err
+		}
+		j.logger.Debug("retention: rolled up",
+			slog.String("source", source),
+			slog.String("resolution", resolution),
+			slog.Time("to", to),
+		)
+	}
+	return nil
+}
+
+// purge deletes raw rows of source before cutoff in batches. Rows that are not yet part of
+// both the hourly and the daily rollup are kept regardless of their age.
+func (j *RetentionJob) purge(ctx context.Context, source string, cutoff time.Time) error {
+	rolledUp, err := rolledUpTo(ctx, j.storage, source)
+	if err != nil {
+		return err
+	}
+	if rolledUp.Before(cutoff) {
+		cutoff = rolledUp
+	}
+
+	var total int64
+	for {
+		deleted, err := j.storage.DeleteBefore(ctx, source, cutoff, j.policy.BatchSize)
+		if err != nil {
+			return err
+		}
+		total += deleted
+		if deleted < int64(j.policy.BatchSize) || !sleepWithin(ctx, retentionBatchPause) {
+			break
+		}
+	}
+
+	if total > 0 {
+		j.logger.Info("retention: purged raw rows",
+			slog.String("source", source),
+			slog.Time("before", cutoff),
+			slog.Int64("rows", total),
+		)
+	}
+	return nil
+}
+
+// rolledUpTo returns the time up to which raw rows of source are part of both the hourly and the daily rollup
+func rolledUpTo(ctx context.Context, retentionStorage storage.RetentionStorage, source string) (time.Time, error) {
+	var rolledUp time.Time
+	for i, resolution := range []string{storage.RollupHourly, storage.RollupDaily} {
+		watermark, err := retentionStorage.RollupWatermark(ctx, source, resolution)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if i == 0 || watermark.Before(rolledUp) {
+			rolledUp = watermark
+		}
+	}
+	return rolledUp, nil
+}
+
+// bucketStart truncates t to the start of its UTC hour or day
+func bucketStart(t time.Time, resolution string) time.Time {
+	t = t.UTC()
+	if resolution == storage.RollupDaily {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return t.Truncate(time.Hour)
+}
@@ -0,0 +1,157 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Shemistan/agent/internal/storage"
+)
+
+type rollupCall struct {
+	source, resolution string
+	from, to           time.Time
+}
+
+type deleteCall struct {
+	source string
+	before time.Time
+}
+
+// MockRetentionStorage implements storage.RetentionStorage interface
+type MockRetentionStorage struct {
+	watermarks map[string]time.Time // keyed by source + "/" + resolution
+	rollups    []rollupCall
+	deletes    []deleteCall
+	expired    map[string]int64 // raw rows left to delete per source
+}
+
+func (m *MockRetentionStorage) RollupWatermark(ctx context.Context, source, resolution string) (time.Time, error) {
+	return m.watermarks[source+"/"+resolution], nil
+}
+
+func (m *MockRetentionStorage) Rollup(ctx context.Context, source, resolution string, from, to time.Time) error {
+	m.rollups = append(m.rollups, rollupCall{source, resolution, from, to})
+	m.watermarks[source+"/"+resolution] = to
+	return nil
+}
+
+func (m *MockRetentionStorage) DeleteBefore(ctx context.Context, source string, before time.Time, limit int) (int64, error) {
+	m.deletes = append(m.deletes, deleteCall{source, before})
+	deleted := min(m.expired[source], int64(limit))
+	m.expired[source] -= deleted
+	return deleted, nil
+}
+
+func TestRetentionJob_RollsUpBeforePurging(t *testing.T) {
+	now := time.Date(2025, 3, 10, 14, 25, 0, 0, time.UTC)
+	hour := time.Date(2025, 3, 10, 14, 0, 0, 0, time.UTC)
+	day := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	mock := &MockRetentionStorage{
+		watermarks: map[string]time.Time{
+			storage.RollupManagerChecks + "/" + storage.RollupHourly: hour.Add(-2 * time.Hour),
+			storage.RollupManagerChecks + "/" + storage.RollupDaily:  day.Add(-24 * time.Hour),
+		},
+		expired: map[string]int64{storage.RollupManagerChecks: 25},
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	job := NewRetentionJob(mock, RetentionPolicy{BatchSize: 10, ManagerChecksTTL: time.Hour}, logger)
+	job.now = func() time.Time { return now }
+
+	job.Run(context.Background())
+
+	expected := []rollupCall{
+		{storage.RollupHealthCalls, storage.RollupHourly, time.Time{}, hour},
+		{storage.RollupHealthCalls, storage.RollupDaily, time.Time{}, day},
+		{storage.RollupManagerChecks, storage.RollupHourly, hour.Add(-2 * time.Hour), hour},
+		{storage.RollupManagerChecks, storage.RollupDaily, day.Add(-24 * time.Hour), day},
+	}
+	if len(mock.rollups) != len(expected) {
+		t.Fatalf("Expected %d rollups, got %+v", len(expected), mock.rollups)
+	}
+	for i, call := range expected {
+		if mock.rollups[i] != call {
+			t.Errorf("Rollup %d: expected %+v, got %+v", i, call, mock.rollups[i])
+		}
+	}
+
+	// health_calls has no TTL; manager_checks are purged in batches up to the daily watermark,
+	// which is older than the TTL cutoff
+	if len(mock.deletes) != 3 {
+		t.Fatalf("Expected 3 delete batches, got %+v", mock.deletes)
+	}
+	for _, call := range mock.deletes {
+		if call.source != storage.RollupManagerChecks || !call.before.Equal(day) {
+			t.Fatalf("Expected manager_checks deleted before %v, got %+v", day, call)
+		}
+	}
+	if mock.expired[storage.RollupManagerChecks] != 0 {
+		t.Fatalf("Expected all expired rows deleted, %d left", mock.expired[storage.RollupManagerChecks])
+	}
+}
+
+func TestRetentionJob_SkipsCompleteBuckets(t *testing.T) {
+	now := time.Date(2025, 3, 10, 14, 25, 0, 0, time.UTC)
+	mock := &MockRetentionStorage{watermarks: map[string]time.Time{}, expired: map[string]int64{}}
+	for _, source := range []string{storage.RollupHealthCalls, storage.RollupManagerChecks} {
+		mock.watermarks[source+"/"+storage.RollupHourly] = bucketStart(now, storage.RollupHourly)
+		mock.watermarks[source+"/"+storage.RollupDaily] = bucketStart(now, storage.RollupDaily)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	job := NewRetentionJob(mock, RetentionPolicy{HealthCallsTTL: 48 * time.Hour}, logger)
+	job.now = func() time.Time { return now }
+
+	job.Run(context.Background())
+
+	if len(mock.rollups) != 0 {
+		t.Fatalf("Expected no rollups within the current buckets, got %+v", mock.rollups)
+	}
+	if len(mock.deletes) != 1 || !mock.deletes[0].before.Equal(now.Add(-48*time.Hour)) {
+		t.Fatalf("Expected health_calls purged at the TTL cutoff, got %+v", mock.deletes)
+	}
+}
+
+func TestRetentionJob_CountsLateRowsWithinLag(t *testing.T) {
+	now := time.Date(2025, 3, 10, 14, 25, 0, 0, time.UTC)
+	mock := &MockRetentionStorage{watermarks: map[string]time.Time{}, expired: map[string]int64{}}
+	for _, source := range []string{storage.RollupHealthCalls, storage.RollupManagerChecks} {
+		mock.watermarks[source+"/"+storage.RollupHourly] = time.Date(2025, 3, 10, 10, 0, 0, 0, time.UTC)
+		mock.watermarks[source+"/"+storage.RollupDaily] = time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	job := NewRetentionJob(mock, RetentionPolicy{RollupLag: 2 * time.Hour}, logger)
+	job.now = func() time.Time { return now }
+	job.Run(context.Background())
+
+	// Without the lag the hour up to 14:00 would be rolled up now
+	hourly := storage.RollupManagerChecks + "/" + storage.RollupHourly
+	if want := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC); !mock.watermarks[hourly].Equal(want) {
+		t.Fatalf("Expected hourly watermark %v, got %v", want, mock.watermarks[hourly])
+	}
+
+	// A check made at 12:40 is replayed from the outbox at 14:30, behind the watermark it would have had
+	late := time.Date(2025, 3, 10, 12, 40, 0, 0, time.UTC)
+	if late.Before(mock.watermarks[hourly]) {
+		t.Fatalf("Expected the late check to be ahead of the watermark %v", mock.watermarks[hourly])
+	}
+
+	mock.rollups = nil
+	now = now.Add(time.Hour)
+	job.Run(context.Background())
+
+	counted := false
+	for _, call := range mock.rollups {
+		if call.source == storage.RollupManagerChecks && call.resolution == storage.RollupHourly &&
+			!late.Before(call.from) && late.Before(call.to) {
+			counted = true
+		}
+	}
+	if !counted {
+		t.Fatalf("Expected a later rollup to cover the late check, got %+v", mock.rollups)
+	}
+}
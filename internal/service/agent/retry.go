@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy configures retries of transient manager check failures.
+// All attempts and backoff delays share the deadline of the check.
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts including the first one, 1 disables retries
+	BaseDelay   time.Duration // backoff before the second attempt, doubled for each further one
+	MaxDelay    time.Duration // upper bound of a single backoff delay, zero means unbounded
+}
+
+// backoff returns the delay before the attempt following attempt n (1-based).
+// Half of the delay is randomised so that checks of many managers do not retry in lockstep.
+func (p RetryPolicy) backoff(n int) time.Duration {
+	delay := p.BaseDelay << (n - 1)
+	if delay <= 0 || (p.MaxDelay > 0 && delay > p.MaxDelay) {
+		delay = p.MaxDelay
+	}
+	if delay <= 1 {
+		return delay
+	}
+	half := delay / 2
+	return half + rand.N(delay-half) // nolint:gosec // jitter does not need a secure source
+}
+
+// sleepWithin waits for delay unless it would overrun the deadline of ctx.
+// It reports false when there is no time left for another attempt.
+func sleepWithin(ctx context.Context, delay time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+		return false
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// isTransientError reports whether a request error is worth retrying:
+// refused or reset connections, unexpected EOFs and timeouts
+func isTransientError(err error) bool {
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// isTransientStatus reports whether an HTTP status indicates a temporarily unavailable manager
+func isTransientStatus(code int) bool {
+	switch code {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
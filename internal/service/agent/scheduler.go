@@ -0,0 +1,174 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/Shemistan/agent/internal/service"
+)
+
+// CheckScheduler periodically runs manager checks in the background
+type CheckScheduler struct {
+	checker  service.ManagerCheckService
+	interval time.Duration
+	jitter   time.Duration
+	logger   *slog.Logger
+
+	mu        sync.Mutex
+	status    service.SchedulerStatus
+	stopLoop  context.CancelFunc
+	cancelRun context.CancelFunc
+	loopDone  chan struct{}
+	runs      sync.WaitGroup
+}
+
+// NewCheckScheduler creates a new CheckScheduler instance
+func NewCheckScheduler(
+	checker service.ManagerCheckService,
+	interval time.Duration,
+	jitter time.Duration,
+	logger *slog.Logger,
+) *CheckScheduler {
+	return &CheckScheduler{
+		checker:  checker,
+		interval: interval,
+		jitter:   jitter,
+		logger:   logger,
+		status:   service.SchedulerStatus{Interval: interval},
+	}
+}
+
+// Start launches the scheduling loop in the background and returns immediately
+func (s *CheckScheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopLoop != nil {
+		return
+	}
+
+	loopCtx, stopLoop := context.WithCancel(ctx)
+	// Runs are not tied to the loop so that Stop can let an in-flight run finish
+	runCtx, cancelRun := context.WithCancel(context.WithoutCancel(ctx))
+
+	s.stopLoop = stopLoop
+	s.cancelRun = cancelRun
+	s.loopDone = make(chan struct{})
+	s.status.Enabled = true
+
+	go s.loop(loopCtx, runCtx)
+
+	s.logger.Info("scheduler: started",
+		slog.Duration("interval", s.interval),
+		slog.Duration("jitter", s.jitter),
+	)
+}
+
+// Stop stops scheduling new runs and waits for an in-flight run to finish.
+// If ctx expires first, the in-flight run is cancelled.
+func (s *CheckScheduler) Stop(ctx context.Context) {
+	s.mu.Lock()
+	stopLoop, cancelRun, loopDone := s.stopLoop, s.cancelRun, s.loopDone
+	s.mu.Unlock()
+
+	if stopLoop == nil {
+		return
+	}
+
+	stopLoop()
+	<-loopDone
+
+	finished := make(chan struct{})
+	go func() {
+		s.runs.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+	case <-ctx.Done():
+		s.logger.Warn("scheduler: cancelling in-flight run on shutdown")
+		cancelRun()
+		<-finished
+	}
+	cancelRun()
+
+	s.logger.Info("scheduler: stopped")
+}
+
+// Status returns a snapshot of the scheduler state
+func (s *CheckScheduler) Status() service.SchedulerStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// loop waits for the next tick and starts a run unless the previous one is still going
+func (s *CheckScheduler) loop(loopCtx, runCtx context.Context) {
+	defer close(s.loopDone)
+
+	for {
+		delay := s.nextDelay()
+		s.mu.Lock()
+		s.status.NextRunAt = time.Now().Add(delay)
+		s.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-loopCtx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.mu.Lock()
+		if s.status.Running {
+			s.status.SkippedTicks++
+			s.mu.Unlock()
+			s.logger.Warn("scheduler: previous run still in progress, skipping tick")
+			continue
+		}
+		s.status.Running = true
+		s.mu.Unlock()
+
+		s.runs.Add(1)
+		go s.run(runCtx)
+	}
+}
+
+// run executes a single round of manager checks and records its timing
+func (s *CheckScheduler) run(ctx context.Context) {
+	defer s.runs.Done()
+
+	startedAt := time.Now()
+	results, err := s.checker.CheckManager(ctx)
+	duration := time.Since(startedAt)
+
+	s.mu.Lock()
+	s.status.Running = false
+	s.status.LastRunAt = startedAt
+	s.status.LastDuration = duration
+	s.status.Runs++
+	s.mu.Unlock()
+
+	if err != nil {
+		s.logger.Error("scheduler: manager check run failed", slog.String("error", err.Error()))
+		return
+	}
+
+	s.logger.Debug("scheduler: manager check run completed",
+		slog.Int("managers", len(results.Results)),
+		slog.Duration("duration", duration),
+	)
+}
+
+// nextDelay returns the interval plus a random jitter
+func (s *CheckScheduler) nextDelay() time.Duration {
+	if s.jitter <= 0 {
+		return s.interval
+	}
+	return s.interval + rand.N(s.jitter) // nolint:gosec // jitter does not need a secure source
+}
@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Shemistan/agent/internal/service"
+)
+
+// MockManagerCheckService implements service.ManagerCheckService interface
+type MockManagerCheckService struct {
+	calls atomic.Int64
+	delay time.Duration
+}
+
+func (m *MockManagerCheckService) CheckManager(ctx context.Context) (service.ManagerCheckResults, error) {
+	m.calls.Add(1)
+	select {
+	case <-time.After(m.delay):
+	case <-ctx.Done():
+	}
+	return service.ManagerCheckResults{}, nil
+}
+
+func TestCheckScheduler_RunsOnInterval(t *testing.T) {
+	checker := &MockManagerCheckService{}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	scheduler := NewCheckScheduler(checker, 10*time.Millisecond, 0, logger)
+
+	scheduler.Start(context.Background())
+	time.Sleep(100 * time.Millisecond)
+	scheduler.Stop(context.Background())
+
+	status := scheduler.Status()
+	if status.Runs < 2 {
+		t.Fatalf("Expected at least 2 runs, got %d", status.Runs)
+	}
+
+	if status.LastRunAt.IsZero() {
+		t.Fatal("Expected last run time to be recorded")
+	}
+
+	calls := checker.calls.Load()
+	time.Sleep(30 * time.Millisecond)
+	if checker.calls.Load() != calls {
+		t.Fatal("Expected no runs after Stop")
+	}
+}
+
+func TestCheckScheduler_SkipsTickWhileRunning(t *testing.T) {
+	checker := &MockManagerCheckService{delay: 80 * time.Millisecond}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	scheduler := NewCheckScheduler(checker, 10*time.Millisecond, 0, logger)
+
+	scheduler.Start(context.Background())
+	time.Sleep(60 * time.Millisecond)
+	scheduler.Stop(context.Background())
+
+	status := scheduler.Status()
+	if checker.calls.Load() != 1 {
+		t.Fatalf("Expected 1 run while the first was in progress, got %d", checker.calls.Load())
+	}
+
+	if status.SkippedTicks == 0 {
+		t.Fatal("Expected skipped ticks to be counted")
+	}
+
+	if status.LastDuration < 80*time.Millisecond {
+		t.Fatalf("Expected Stop to wait for the in-flight run, last duration %v", status.LastDuration)
+	}
+}
@@ -2,164 +2,243 @@ package agent
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/tls"
 	"fmt"
-	"io"
 	"log/slog"
+	"net"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/Shemistan/agent/internal/service"
 	"github.com/Shemistan/agent/internal/storage"
 )
 
-// HealthService implements the health check service
-type HealthService struct {
-	healthStorage storage.HealthStorage
-	logger        *slog.Logger
+// ManagerCheckService implements the manager check service
+type ManagerCheckService struct {
+	httpClient          *http.Client
+	tlsConfig           *tls.Config
+	probers             map[string]Prober
+	managerCheckStorage storage.ManagerCheckStorage
+	targets             []service.ManagerTarget
+	concurrency         int
+	checkTimeout        time.Duration
+	retry               RetryPolicy
+	statusPolicy        StatusPolicy
+	observers           []service.CheckObserver
+	logger              *slog.Logger
 }
 
-// NewHealthService creates a new HealthService instance
-func NewHealthService(healthStorage storage.HealthStorage, logger *slog.Logger) *HealthService {
-	return &HealthService{
-		healthStorage: healthStorage,
-		logger:        logger,
+// ManagerCheckOption configures optional ManagerCheckService behaviour
+type ManagerCheckOption func(*ManagerCheckService)
+
+// WithConcurrency limits how many managers are checked at the same time
+func WithConcurrency(n int) ManagerCheckOption {
+	return func(s *ManagerCheckService) {
+		if n > 0 {
+			s.concurrency = n
+		}
 	}
 }
 
-// HandleHealth records a health check call
-func (s *HealthService) HandleHealth(ctx context.Context) error {
-	return s.healthStorage.SaveHealthCall(ctx, time.Now())
+// WithCheckTimeout bounds the duration of each individual manager check
+func WithCheckTimeout(timeout time.Duration) ManagerCheckOption {
+	return func(s *ManagerCheckService) {
+		s.checkTimeout = timeout
+	}
 }
 
-// ManagerCheckService implements the manager check service
-type ManagerCheckService struct {
-	httpClient          *http.Client
-	managerCheckStorage storage.ManagerCheckStorage
-	managerURLs         []string
-	logger              *slog.Logger
+// WithTLSConfig sets the client TLS configuration of the manager targets.
+// Its CA, client certificate and server name are only used for targets with ManagerTLS.
+func WithTLSConfig(cfg *tls.Config) ManagerCheckOption {
+	return func(s *ManagerCheckService) {
+		s.tlsConfig = cfg
+	}
+}
+
+// WithProber checks targets of the given probe type with p instead of the built-in prober
+func WithProber(probeType string, p Prober) ManagerCheckOption {
+	return func(s *ManagerCheckService) {
+		s.probers[probeType] = p
+	}
+}
+
+// WithRetry retries transient check failures according to policy
+func WithRetry(policy RetryPolicy) ManagerCheckOption {
+	return func(s *ManagerCheckService) {
+		if policy.MaxAttempts > 0 {
+			s.retry = policy
+		}
+	}
+}
+
+// WithStatusPolicy sets how the overall status of a check run is derived, PolicyAnyDown by default
+func WithStatusPolicy(policy StatusPolicy) ManagerCheckOption {
+	return func(s *ManagerCheckService) {
+		s.statusPolicy = policy
+	}
+}
+
+// WithObserver reports every check outcome to observer; it may be given more than once
+func WithObserver(observer service.CheckObserver) ManagerCheckOption {
+	return func(s *ManagerCheckService) {
+		s.observers = append(s.observers, observer)
+	}
 }
 
 // NewManagerCheckService creates a new ManagerCheckService instance
 func NewManagerCheckService(
 	httpClient *http.Client,
 	managerCheckStorage storage.ManagerCheckStorage,
-	managerURLs []string,
+	targets []service.ManagerTarget,
 	logger *slog.Logger,
+	opts ...ManagerCheckOption,
 ) *ManagerCheckService {
-	return &ManagerCheckService{
+	s := &ManagerCheckService{
 		httpClient:          httpClient,
+		probers:             make(map[string]Prober),
 		managerCheckStorage: managerCheckStorage,
-		managerURLs:         managerURLs,
+		targets:             compileTargets(targets),
+		concurrency:         defaultCheckConcurrency,
+		retry:               RetryPolicy{MaxAttempts: 1},
 		logger:              logger,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	builtin := map[string]Prober{
+		service.ProbeHTTP:     NewHTTPProber(httpClient, s.tlsConfig, logger),
+		service.ProbeTCP:      NewTCPProber(),
+		service.ProbeTLS:      NewTLSProber(s.tlsConfig),
+		service.ProbeDNS:      NewDNSProber(net.DefaultResolver),
+		service.ProbeGRPC:     NewGRPCProber(s.tlsConfig),
+		service.ProbePostgres: NewPostgresProber(),
+	}
+	for probeType, p := range builtin {
+		if _, ok := s.probers[probeType]; !ok {
+			s.probers[probeType] = p
+		}
+	}
+	return s
 }
 
-// healthResponse represents the expected response from manager /health
-type healthResponse struct {
-	Status string `json:"status"`
+// compileTargets returns a copy of targets with the patterns of their matches assertions compiled once,
+// so that checks do not compile them on every run. Invalid patterns are left to fail the check.
+func compileTargets(targets []service.ManagerTarget) []service.ManagerTarget {
+	compiled := make([]service.ManagerTarget, len(targets))
+	for i, target := range targets {
+		target.Assertions = compileAssertions(target.Assertions)
+		compiled[i] = target
+	}
+	return compiled
 }
 
-// CheckManager checks all configured manager services and records results
+// defaultCheckConcurrency is the number of managers checked in parallel by default
+const defaultCheckConcurrency = 4
+
+// CheckManager checks all configured manager services in parallel and records results.
+// Results are returned in configuration order along with the overall status.
 func (s *ManagerCheckService) CheckManager(ctx context.Context) (service.ManagerCheckResults, error) {
 	results := service.ManagerCheckResults{
-		Results: make([]service.ManagerCheckResult, 0, len(s.managerURLs)),
+		Results: make([]service.ManagerCheckResult, len(s.targets)),
 	}
 
-	// Check each manager URL
-	for _, managerURL := range s.managerURLs {
-		result := s.checkSingleManager(ctx, managerURL)
-		results.Results = append(results.Results, result)
+	sem := make(chan struct{}, s.concurrency)
+	var wg sync.WaitGroup
+
+	// Check each manager, at most s.concurrency at a time
+	for i, target := range s.targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				results.Results[i] = service.ManagerCheckResult{
+					ManagerName:  target.Name,
+					ManagerURL:   target.URL,
+					Status:       service.StatusError,
+					ErrorMessage: fmt.Sprintf("check not started: %v", ctx.Err()),
+				}
+				return
+			}
+
+			results.Results[i] = s.checkSingleManager(ctx, target)
+		}()
 	}
+	wg.Wait()
 
+	results.Status = s.statusPolicy.overall(results.Results, s.targets)
 	return results, nil
 }
 
-// checkSingleManager checks a single manager service health
-func (s *ManagerCheckService) checkSingleManager(ctx context.Context, managerURL string) service.ManagerCheckResult {
-	result := service.ManagerCheckResult{
-		ManagerURL: managerURL,
-		Status:     "error",
-	}
-
-	url := fmt.Sprintf("%s/health", managerURL)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		errMsg := fmt.Sprintf("failed to create request: %v", err)
-		result.ErrorMessage = errMsg
-		s.logger.Error("manager check: request creation failed", slog.String("url", managerURL), slog.String("error", errMsg))
-		s.saveResult(ctx, result)
-		return result
-	}
-
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		errMsg := fmt.Sprintf("HTTP request failed: %v", err)
-		result.ErrorMessage = errMsg
-		s.logger.Error("manager check: HTTP request failed", slog.String("url", managerURL), slog.String("error", errMsg))
-		s.saveResult(ctx, result)
-		return result
-	}
-	defer func() {
-		if cerr := resp.Body.Close(); cerr != nil {
-			s.logger.Warn("manager check: failed to close response body", slog.String("url", managerURL), slog.String("error", cerr.Error()))
-		}
-	}()
-
-	result.HTTPStatus = resp.StatusCode
-
-	// Check HTTP status code
-	if resp.StatusCode != http.StatusOK {
-		errMsg := fmt.Sprintf("unexpected HTTP status: %d", resp.StatusCode)
-		result.ErrorMessage = errMsg
-		s.logger.Error("manager check: unexpected status", slog.String("url", managerURL), slog.Int("status", resp.StatusCode))
-		s.saveResult(ctx, result)
-		return result
-	}
-
-	// Parse response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		errMsg := fmt.Sprintf("failed to read response body: %v", err)
-		result.ErrorMessage = errMsg
-		s.logger.Error("manager check: failed to read body", slog.String("url", managerURL), slog.String("error", errMsg))
-		s.saveResult(ctx, result)
-		return result
-	}
-
-	var healthResp healthResponse
-	if err := json.Unmarshal(body, &healthResp); err != nil {
-		errMsg := fmt.Sprintf("failed to parse response: %v", err)
-		result.ErrorMessage = errMsg
-		s.logger.Error("manager check: failed to parse response", slog.String("url", managerURL), slog.String("error", errMsg))
-		s.saveResult(ctx, result)
-		return result
-	}
-
-	// Check if status is "success"
-	if healthResp.Status != "success" {
-		errMsg := fmt.Sprintf("manager returned status: %s", healthResp.Status)
-		result.ErrorMessage = errMsg
-		result.Status = "error"
-		s.logger.Error("manager check: manager returned error status", slog.String("url", managerURL), slog.String("status", healthResp.Status))
-		s.saveResult(ctx, result)
-		return result
-	}
-
-	// Success case
-	result.Status = "success"
-	result.ErrorMessage = ""
-	s.logger.Info("manager check: success", slog.String("url", managerURL))
+// checkSingleManager checks a single manager under its own timeout, retrying
+// transient failures within it, and saves the result
+func (s *ManagerCheckService) checkSingleManager(ctx context.Context, target service.ManagerTarget) service.ManagerCheckResult {
+	checkCtx := ctx
+	timeout := s.checkTimeout
+	if target.Timeout > 0 {
+		timeout = target.Timeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		checkCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	startedAt := time.Now()
+	result := s.probeWithRetry(checkCtx, target)
+	result.ManagerName = target.Name
+	result.ManagerURL = target.URL
+	result.Timing.Total = time.Since(startedAt)
+
 	s.saveResult(ctx, result)
+	// The save may only queue the check, so observers can run before it is persisted
+	for _, observer := range s.observers {
+		observer.ObserveManagerCheck(result, result.Timing.Total)
+	}
 	return result
 }
 
+// probeWithRetry probes the target with the prober of its type, retrying transient failures
+// while the retry policy and the deadline of ctx allow
+func (s *ManagerCheckService) probeWithRetry(ctx context.Context, target service.ManagerTarget) service.ManagerCheckResult {
+	prober, ok := s.probers[probeType(target)]
+	if !ok {
+		return service.ManagerCheckResult{
+			Status:       service.StatusError,
+			ErrorMessage: fmt.Sprintf("unknown probe type: %s", target.Type),
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		result, transient := prober.Probe(ctx, target)
+		result.Attempts = attempt
+
+		if !transient || attempt >= s.retry.MaxAttempts {
+			return result
+		}
+		delay := s.retry.backoff(attempt)
+		if !sleepWithin(ctx, delay) {
+			return result
+		}
+		s.logger.Warn("manager check: retrying after transient failure",
+			slog.String("url", target.URL),
+			slog.Int("attempt", attempt+1),
+			slog.Duration("backoff", delay),
+		)
+	}
+}
+
 // saveResult saves the check result to the database, logging errors without failing
 func (s *ManagerCheckService) saveResult(ctx context.Context, result service.ManagerCheckResult) {
 	check := storage.ManagerCheck{
 		CheckedAt:    time.Now(),
 		ManagerURL:   result.ManagerURL,
-		Status:       result.Status,
+		Status:       string(result.Status),
 		HTTPStatus:   nil,
 		ErrorMessage: nil,
 	}
@@ -172,7 +251,23 @@ func (s *ManagerCheckService) saveResult(ctx context.Context, result service.Man
 		check.ErrorMessage = &result.ErrorMessage
 	}
 
+	check.DNSMillis = millis(result.Timing.DNS)
+	check.ConnectMillis = millis(result.Timing.Connect)
+	check.TLSMillis = millis(result.Timing.TLSHandshake)
+	check.TTFBMillis = millis(result.Timing.FirstByte)
+	check.TotalMillis = millis(result.Timing.Total)
+	check.Attempts = max(result.Attempts, 1)
+
 	if err := s.managerCheckStorage.SaveManagerCheck(ctx, check); err != nil {
 		s.logger.Error("failed to save manager check result", slog.String("error", err.Error()))
 	}
 }
+
+// millis converts a phase duration to milliseconds for storage, nil if the phase did not happen
+func millis(d time.Duration) *float64 {
+	if d <= 0 {
+		return nil
+	}
+	ms := float64(d) / float64(time.Millisecond)
+	return &ms
+}
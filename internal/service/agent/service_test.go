@@ -2,32 +2,62 @@ package agent
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/Shemistan/agent/internal/service"
 	"github.com/Shemistan/agent/internal/storage"
 )
 
+// urlTargets builds targets with the default /health contract for the given base URLs
+func urlTargets(urls ...string) []service.ManagerTarget {
+	targets := make([]service.ManagerTarget, 0, len(urls))
+	for _, url := range urls {
+		targets = append(targets, service.ManagerTarget{
+			Name:          url,
+			URL:           url,
+			ExpectedField: "status",
+			ExpectedValue: "success",
+		})
+	}
+	return targets
+}
+
 // MockHealthStorage implements storage.HealthStorage interface
 type MockHealthStorage struct {
-	calls int
+	mu    sync.Mutex
+	calls []storage.HealthCall
+	err   error
 }
 
-func (m *MockHealthStorage) SaveHealthCall(ctx context.Context, calledAt time.Time) error {
-	m.calls++
+func (m *MockHealthStorage) SaveHealthCall(ctx context.Context, call storage.HealthCall) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.calls = append(m.calls, call)
 	return nil
 }
 
 // MockManagerCheckStorage implements storage.ManagerCheckStorage interface
 type MockManagerCheckStorage struct {
+	mu          sync.Mutex
 	savedChecks []storage.ManagerCheck
 }
 
 func (m *MockManagerCheckStorage) SaveManagerCheck(ctx context.Context, check storage.ManagerCheck) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.savedChecks = append(m.savedChecks, check)
 	return nil
 }
@@ -40,18 +70,36 @@ func mustWrite(t *testing.T, w http.ResponseWriter, data []byte) {
 	}
 }
 
-func TestHealthService_HandleHealth(t *testing.T) {
+func TestHealthService_RecordHealthCall(t *testing.T) {
 	mockStorage := &MockHealthStorage{}
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
-	service := NewHealthService(mockStorage, logger)
-
-	err := service.HandleHealth(context.Background())
-	if err != nil {
-		t.Fatalf("HandleHealth failed: %v", err)
+	healthService := NewHealthService(mockStorage, HealthCallPolicy{}, logger)
+	healthService.Start()
+
+	calledAt := time.Now()
+	healthService.RecordHealthCall(service.HealthCall{
+		CalledAt:  calledAt,
+		CallerIP:  "10.0.0.7",
+		UserAgent: "ELB-HealthChecker/2.0",
+		Latency:   3 * time.Millisecond,
+	})
+	healthService.Stop(context.Background())
+
+	if len(mockStorage.calls) != 1 {
+		t.Fatalf("Expected 1 call, got %d", len(mockStorage.calls))
 	}
-
-	if mockStorage.calls != 1 {
-		t.Fatalf("Expected 1 call, got %d", mockStorage.calls)
+	call := mockStorage.calls[0]
+	if !call.CalledAt.Equal(calledAt) || call.Calls != 1 {
+		t.Errorf("Expected call at %v standing for 1 call, got %+v", calledAt, call)
+	}
+	if call.CallerIP == nil || *call.CallerIP != "10.0.0.7" {
+		t.Errorf("Expected caller IP 10.0.0.7, got %v", call.CallerIP)
+	}
+	if call.UserAgent == nil || *call.UserAgent != "ELB-HealthChecker/2.0" {
+		t.Errorf("Expected user agent to be stored, got %v", call.UserAgent)
+	}
+	if call.LatencyMillis == nil || *call.LatencyMillis != 3 {
+		t.Errorf("Expected latency of 3ms, got %v", call.LatencyMillis)
 	}
 }
 
@@ -72,7 +120,7 @@ func TestManagerCheckService_CheckManager_Success(t *testing.T) {
 	service := NewManagerCheckService(
 		server.Client(),
 		mockStorage,
-		[]string{server.URL},
+		urlTargets(server.URL),
 		logger,
 	)
 
@@ -117,7 +165,7 @@ func TestManagerCheckService_CheckManager_Failure_BadStatus(t *testing.T) {
 	service := NewManagerCheckService(
 		server.Client(),
 		mockStorage,
-		[]string{server.URL},
+		urlTargets(server.URL),
 		logger,
 	)
 
@@ -152,7 +200,7 @@ func TestManagerCheckService_CheckManager_Failure_NonOKStatus(t *testing.T) {
 	service := NewManagerCheckService(
 		server.Client(),
 		mockStorage,
-		[]string{server.URL},
+		urlTargets(server.URL),
 		logger,
 	)
 
@@ -188,7 +236,7 @@ func TestManagerCheckService_CheckManager_Failure_ConnectionError(t *testing.T)
 	service := NewManagerCheckService(
 		client,
 		mockStorage,
-		[]string{"http://invalid-host-that-does-not-exist.example.com"},
+		urlTargets("http://invalid-host-that-does-not-exist.example.com"),
 		logger,
 	)
 
@@ -210,3 +258,491 @@ func TestManagerCheckService_CheckManager_Failure_ConnectionError(t *testing.T)
 		t.Fatalf("Expected 1 saved check, got %d", len(mockStorage.savedChecks))
 	}
 }
+
+func TestManagerCheckService_CheckManager_RecordsTLSDetails(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		mustWrite(t, w, []byte(`{"status":"success"}`))
+	}))
+	defer server.Close()
+
+	mockStorage := &MockManagerCheckStorage{}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	service := NewManagerCheckService(
+		server.Client(),
+		mockStorage,
+		urlTargets(server.URL),
+		logger,
+	)
+
+	results, err := service.CheckManager(context.Background())
+	if err != nil {
+		t.Fatalf("CheckManager failed: %v", err)
+	}
+
+	result := results.Results[0]
+	if result.Status != "success" {
+		t.Fatalf("Expected success, got %s (%s)", result.Status, result.ErrorMessage)
+	}
+
+	if result.TLSVersion == "" {
+		t.Fatal("Expected negotiated TLS version to be recorded")
+	}
+
+	if !result.CertNotAfter.Equal(server.Certificate().NotAfter) {
+		t.Fatalf("Expected cert expiry %v, got %v", server.Certificate().NotAfter, result.CertNotAfter)
+	}
+}
+
+func TestManagerCheckService_CheckManager_ManagerTLSIsOptIn(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(t, w, []byte(`{"status":"success"}`))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert, MinVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+
+	// The client trusts the test certificate the way a public dependency is trusted by system roots,
+	// while the manager TLS config has its own CA, server name and client certificate
+	var clientCertRequests atomic.Int32
+	managerTLS := &tls.Config{
+		RootCAs:    x509.NewCertPool(),
+		ServerName: "manager.internal",
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			clientCertRequests.Add(1)
+			return &tls.Certificate{}, nil
+		},
+		MinVersion: tls.VersionTLS12,
+	}
+
+	service := NewManagerCheckService(
+		server.Client(),
+		&MockManagerCheckStorage{},
+		urlTargets(server.URL),
+		slog.New(slog.NewTextHandler(os.Stdout, nil)),
+		WithTLSConfig(managerTLS),
+	)
+
+	results, err := service.CheckManager(context.Background())
+	if err != nil {
+		t.Fatalf("CheckManager failed: %v", err)
+	}
+	if result := results.Results[0]; result.Status != "success" {
+		t.Fatalf("Expected the https target without manager_tls to succeed, got %s (%s)", result.Status, result.ErrorMessage)
+	}
+	if clientCertRequests.Load() != 0 {
+		t.Error("Expected no manager client certificate for a target without manager_tls")
+	}
+
+	targets := urlTargets(server.URL)
+	targets[0].ManagerTLS = true
+	service = NewManagerCheckService(
+		server.Client(),
+		&MockManagerCheckStorage{},
+		targets,
+		slog.New(slog.NewTextHandler(os.Stdout, nil)),
+		WithTLSConfig(managerTLS),
+	)
+
+	results, err = service.CheckManager(context.Background())
+	if err != nil {
+		t.Fatalf("CheckManager failed: %v", err)
+	}
+	if result := results.Results[0]; result.Status != "error" || !strings.Contains(result.ErrorMessage, "certificate") {
+		t.Fatalf("Expected the manager CA to be used for the manager_tls target, got %s (%s)", result.Status, result.ErrorMessage)
+	}
+}
+
+func TestManagerCheckService_CheckManager_ParallelKeepsOrder(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int64
+	newServer := func(delay time.Duration) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			current := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				observed := maxInFlight.Load()
+				if current <= observed || maxInFlight.CompareAndSwap(observed, current) {
+					break
+				}
+			}
+
+			time.Sleep(delay)
+			w.WriteHeader(http.StatusOK)
+			mustWrite(t, w, []byte(`{"status":"success"}`))
+		}))
+	}
+
+	slow := newServer(100 * time.Millisecond)
+	defer slow.Close()
+	fast1 := newServer(10 * time.Millisecond)
+	defer fast1.Close()
+	fast2 := newServer(10 * time.Millisecond)
+	defer fast2.Close()
+
+	mockStorage := &MockManagerCheckStorage{}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	urls := []string{slow.URL, fast1.URL, fast2.URL}
+	service := NewManagerCheckService(
+		http.DefaultClient,
+		mockStorage,
+		urlTargets(urls...),
+		logger,
+		WithConcurrency(2),
+	)
+
+	results, err := service.CheckManager(context.Background())
+	if err != nil {
+		t.Fatalf("CheckManager failed: %v", err)
+	}
+
+	for i, result := range results.Results {
+		if result.ManagerURL != urls[i] {
+			t.Fatalf("Expected result %d for %s, got %s", i, urls[i], result.ManagerURL)
+		}
+		if result.Status != "success" {
+			t.Fatalf("Expected success for %s, got %s", result.ManagerURL, result.Status)
+		}
+	}
+
+	if maxInFlight.Load() != 2 {
+		t.Fatalf("Expected 2 concurrent checks, got %d", maxInFlight.Load())
+	}
+
+	if len(mockStorage.savedChecks) != 3 {
+		t.Fatalf("Expected 3 saved checks, got %d", len(mockStorage.savedChecks))
+	}
+}
+
+func TestManagerCheckService_CheckManager_PerCheckTimeout(t *testing.T) {
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer hung.Close()
+
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		mustWrite(t, w, []byte(`{"status":"success"}`))
+	}))
+	defer healthy.Close()
+
+	mockStorage := &MockManagerCheckStorage{}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	service := NewManagerCheckService(
+		http.DefaultClient,
+		mockStorage,
+		urlTargets(hung.URL, healthy.URL),
+		logger,
+		WithConcurrency(1),
+		WithCheckTimeout(50*time.Millisecond),
+	)
+
+	results, err := service.CheckManager(context.Background())
+	if err != nil {
+		t.Fatalf("CheckManager failed: %v", err)
+	}
+
+	if results.Results[0].Status != "error" {
+		t.Fatalf("Expected hung manager to fail, got %s", results.Results[0].Status)
+	}
+
+	if results.Results[1].Status != "success" {
+		t.Fatalf("Expected healthy manager to succeed, got %s", results.Results[1].Status)
+	}
+
+	if len(mockStorage.savedChecks) != 2 {
+		t.Fatalf("Expected timed out check to be saved too, got %d saved", len(mockStorage.savedChecks))
+	}
+}
+
+func TestManagerCheckService_CheckManager_RecordsTiming(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+		mustWrite(t, w, []byte(`{"status":"success"}`))
+	}))
+	defer server.Close()
+
+	mockStorage := &MockManagerCheckStorage{}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	service := NewManagerCheckService(
+		server.Client(),
+		mockStorage,
+		urlTargets(server.URL),
+		logger,
+	)
+
+	results, err := service.CheckManager(context.Background())
+	if err != nil {
+		t.Fatalf("CheckManager failed: %v", err)
+	}
+
+	timing := results.Results[0].Timing
+	if timing.Connect <= 0 || timing.TLSHandshake <= 0 {
+		t.Fatalf("Expected connect and TLS phases to be recorded, got %+v", timing)
+	}
+
+	if timing.FirstByte < 20*time.Millisecond || timing.Total < timing.FirstByte {
+		t.Fatalf("Expected TTFB >= 20ms and total >= TTFB, got %+v", timing)
+	}
+
+	saved := mockStorage.savedChecks[0]
+	if saved.TotalMillis == nil || saved.TTFBMillis == nil || saved.TLSMillis == nil {
+		t.Fatalf("Expected timing to be saved, got %+v", saved)
+	}
+
+	if saved.DNSMillis != nil {
+		t.Fatalf("Expected no DNS phase for an IP address, got %v", *saved.DNSMillis)
+	}
+}
+
+func TestManagerCheckService_CheckManager_RetriesTransientStatus(t *testing.T) {
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		mustWrite(t, w, []byte(`{"status":"success"}`))
+	}))
+	defer server.Close()
+
+	mockStorage := &MockManagerCheckStorage{}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	service := NewManagerCheckService(
+		server.Client(),
+		mockStorage,
+		urlTargets(server.URL),
+		logger,
+		WithRetry(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}),
+	)
+
+	results, err := service.CheckManager(context.Background())
+	if err != nil {
+		t.Fatalf("CheckManager failed: %v", err)
+	}
+
+	result := results.Results[0]
+	if result.Status != "success" {
+		t.Fatalf("Expected success after retries, got %s (%s)", result.Status, result.ErrorMessage)
+	}
+
+	if result.Attempts != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", result.Attempts)
+	}
+
+	if len(mockStorage.savedChecks) != 1 || mockStorage.savedChecks[0].Attempts != 3 {
+		t.Fatalf("Expected one saved check with 3 attempts, got %+v", mockStorage.savedChecks)
+	}
+}
+
+func TestManagerCheckService_CheckManager_NoRetryOnPermanentFailure(t *testing.T) {
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	mockStorage := &MockManagerCheckStorage{}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	service := NewManagerCheckService(
+		server.Client(),
+		mockStorage,
+		urlTargets(server.URL),
+		logger,
+		WithRetry(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}),
+	)
+
+	results, err := service.CheckManager(context.Background())
+	if err != nil {
+		t.Fatalf("CheckManager failed: %v", err)
+	}
+
+	if calls.Load() != 1 || results.Results[0].Attempts != 1 {
+		t.Fatalf("Expected a single attempt for HTTP 500, got %d calls", calls.Load())
+	}
+}
+
+func TestManagerCheckService_CheckManager_RetriesStayWithinDeadline(t *testing.T) {
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	mockStorage := &MockManagerCheckStorage{}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	service := NewManagerCheckService(
+		server.Client(),
+		mockStorage,
+		urlTargets(server.URL),
+		logger,
+		WithCheckTimeout(100*time.Millisecond),
+		WithRetry(RetryPolicy{MaxAttempts: 10, BaseDelay: 40 * time.Millisecond}),
+	)
+
+	startedAt := time.Now()
+	results, err := service.CheckManager(context.Background())
+	if err != nil {
+		t.Fatalf("CheckManager failed: %v", err)
+	}
+
+	if elapsed := time.Since(startedAt); elapsed > 150*time.Millisecond {
+		t.Fatalf("Expected retries to stop at the check deadline, took %v", elapsed)
+	}
+
+	result := results.Results[0]
+	if result.Status != "error" || result.Attempts < 2 || result.Attempts >= 10 {
+		t.Fatalf("Expected a failed check with a few attempts, got %s after %d", result.Status, result.Attempts)
+	}
+
+	// The last attempt may run out of time before its request reaches the server
+	if requests := int(calls.Load()); requests != result.Attempts && requests != result.Attempts-1 {
+		t.Fatalf("Expected attempts to match requests, got %d attempts and %d requests", result.Attempts, requests)
+	}
+}
+
+func TestManagerCheckService_CheckManager_CustomTarget(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/health" || r.Header.Get("X-Token") != "secret" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		mustWrite(t, w, []byte(`{"state":"ok","ready":true}`))
+	}))
+	defer server.Close()
+
+	mockStorage := &MockManagerCheckStorage{}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	checkService := NewManagerCheckService(
+		server.Client(),
+		mockStorage,
+		[]service.ManagerTarget{
+			{
+				Name:           "custom",
+				URL:            server.URL,
+				HealthPath:     "/api/v1/health",
+				Method:         http.MethodPost,
+				Headers:        map[string]string{"X-Token": "secret"},
+				ExpectedStatus: []int{http.StatusOK, http.StatusAccepted},
+				ExpectedField:  "ready",
+				ExpectedValue:  "true",
+			},
+			{
+				Name:          "wrong-value",
+				URL:           server.URL,
+				HealthPath:    "/api/v1/health",
+				Method:        http.MethodPost,
+				Headers:       map[string]string{"X-Token": "secret"},
+				ExpectedField: "state",
+				ExpectedValue: "ok",
+			},
+		},
+		logger,
+	)
+
+	results, err := checkService.CheckManager(context.Background())
+	if err != nil {
+		t.Fatalf("CheckManager failed: %v", err)
+	}
+
+	if results.Results[0].Status != "success" || results.Results[0].ManagerName != "custom" {
+		t.Fatalf("Expected custom target to succeed, got %+v", results.Results[0])
+	}
+
+	// 202 is not accepted by default
+	if results.Results[1].Status != "error" || results.Results[1].HTTPStatus != http.StatusAccepted {
+		t.Fatalf("Expected default expected status to reject 202, got %+v", results.Results[1])
+	}
+}
+
+func TestManagerCheckService_CheckManager_ReportsFailedAssertions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		mustWrite(t, w, []byte(`{"status":"success","components":{"db":"ok","queue":"degraded"}}`))
+	}))
+	defer server.Close()
+
+	targets := urlTargets(server.URL)
+	targets[0].Assertions = []service.Assertion{{Path: "components.*", Value: "ok"}}
+
+	mockStorage := &MockManagerCheckStorage{}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	checkService := NewManagerCheckService(server.Client(), mockStorage, targets, logger)
+
+	results, err := checkService.CheckManager(context.Background())
+	if err != nil {
+		t.Fatalf("CheckManager failed: %v", err)
+	}
+
+	result := results.Results[0]
+	if result.Status != "error" || len(result.FailedAssertions) != 1 {
+		t.Fatalf("Expected one failed assertion, got %s with %+v", result.Status, result.FailedAssertions)
+	}
+
+	expected := `1 assertion(s) failed: body components.queue equals "ok": got "degraded"`
+	if result.ErrorMessage != expected {
+		t.Fatalf("Expected error message %q, got %q", expected, result.ErrorMessage)
+	}
+}
+
+func TestManagerCheckService_CheckManager_MaxBodyBytes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		mustWrite(t, w, []byte(`{"status":"success","padding":"0123456789"}`))
+	}))
+	defer server.Close()
+
+	targets := urlTargets(server.URL)
+	targets[0].MaxBodyBytes = 16
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	checkService := NewManagerCheckService(server.Client(), &MockManagerCheckStorage{}, targets, logger)
+
+	results, err := checkService.CheckManager(context.Background())
+	if err != nil {
+		t.Fatalf("CheckManager failed: %v", err)
+	}
+
+	if results.Results[0].Status != "error" || results.Results[0].ErrorMessage != "response body exceeds 16 bytes" {
+		t.Fatalf("Expected body size error, got %+v", results.Results[0])
+	}
+}
+
+func TestManagerCheckService_CheckManager_DegradedAssertion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		mustWrite(t, w, []byte(`{"status":"success","components":{"db":"ok","cache":"down"}}`))
+	}))
+	defer server.Close()
+
+	targets := urlTargets(server.URL, "http://127.0.0.1:1")
+	targets[0].Assertions = []service.Assertion{
+		{Path: "components.cache", Value: "ok", Severity: service.StatusDegraded},
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	checkService := NewManagerCheckService(server.Client(), &MockManagerCheckStorage{}, targets, logger,
+		WithStatusPolicy(StatusPolicy{Mode: service.PolicyQuorum, Quorum: 1}),
+	)
+
+	results, err := checkService.CheckManager(context.Background())
+	if err != nil {
+		t.Fatalf("CheckManager failed: %v", err)
+	}
+
+	if results.Results[0].Status != service.StatusDegraded {
+		t.Fatalf("Expected degraded status, got %s: %s", results.Results[0].Status, results.Results[0].ErrorMessage)
+	}
+	if results.Results[1].Status != service.StatusError {
+		t.Fatalf("Expected unreachable manager to fail, got %s", results.Results[1].Status)
+	}
+	if results.Status != service.StatusDegraded {
+		t.Fatalf("Expected degraded overall status with quorum of one, got %s", results.Status)
+	}
+}
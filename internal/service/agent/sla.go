@@ -0,0 +1,143 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/Shemistan/agent/internal/service"
+	"github.com/Shemistan/agent/internal/storage"
+)
+
+// slaWindow is a rolling window reported by SLAService
+type slaWindow struct {
+	name     string
+	duration time.Duration
+}
+
+// slaWindows are reported in this order; the last one must be the longest
+var slaWindows = []slaWindow{
+	{name: "1h", duration: time.Hour},
+	{name: "24h", duration: 24 * time.Hour},
+	{name: "7d", duration: 7 * 24 * time.Hour},
+	{name: "30d", duration: 30 * 24 * time.Hour},
+}
+
+// SLAService computes manager availability from stored checks
+type SLAService struct {
+	slaStorage  storage.ManagerSLAStorage
+	managerURLs []string
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+// NewSLAService creates a new SLAService instance
+func NewSLAService(
+	slaStorage storage.ManagerSLAStorage,
+	managerURLs []string,
+	logger *slog.Logger,
+) *SLAService {
+	return &SLAService{
+		slaStorage:  slaStorage,
+		managerURLs: managerURLs,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// ManagerSLA reports availability of a configured manager over all rolling windows
+func (s *SLAService) ManagerSLA(ctx context.Context, managerURL string) (service.ManagerSLAReport, error) {
+	if !slices.Contains(s.managerURLs, managerURL) {
+		return service.ManagerSLAReport{}, service.ErrUnknownManager
+	}
+
+	// Load the longest window once and clip it for the shorter ones
+	to := s.now()
+	longest := slaWindows[len(slaWindows)-1]
+	runs, err := s.slaStorage.ListStatusRuns(ctx, managerURL, to.Add(-longest.duration), to)
+	if err != nil {
+		return service.ManagerSLAReport{}, err
+	}
+
+	// Raw checks past the retention TTL are purged, so windows reaching further back
+	// are reported from the oldest stored check on
+	oldest, err := s.slaStorage.OldestManagerCheck(ctx, managerURL)
+	if err != nil {
+		return service.ManagerSLAReport{}, err
+	}
+
+	report := service.ManagerSLAReport{
+		ManagerURL: managerURL,
+		Windows:    make([]service.ManagerSLA, 0, len(slaWindows)),
+	}
+	for _, window := range slaWindows {
+		from := to.Add(-window.duration)
+		partial := oldest.After(from)
+		if partial {
+			from = oldest
+		}
+		sla := computeSLA(runs, from, to)
+		sla.Window = window.name
+		sla.Partial = partial
+		report.Windows = append(report.Windows, sla)
+	}
+	return report, nil
+}
+
+// computeSLA derives availability figures for [from, to) from status runs.
+// A run lasts until the next run starts; the last run lasts until to.
+func computeSLA(runs []storage.StatusRun, from, to time.Time) service.ManagerSLA {
+	sla := service.ManagerSLA{From: from, To: to}
+
+	var up, degraded, down, recovered time.Duration
+	recoveries := 0
+	for i, run := range runs {
+		start, end := run.StartedAt, to
+		if i+1 < len(runs) {
+			end = runs[i+1].StartedAt
+		}
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if !start.Before(end) {
+			continue
+		}
+
+		duration := end.Sub(start)
+		if status := service.Status(run.Status); status.IsUp() {
+			up += duration
+			if status == service.StatusDegraded {
+				degraded += duration
+			}
+			continue
+		}
+
+		down += duration
+		sla.Outages++
+		sla.LongestOutage = max(sla.LongestOutage, duration)
+		if i+1 < len(runs) {
+			recovered += duration
+			recoveries++
+		}
+	}
+
+	sla.Observed = up + down
+	if sla.Observed == 0 {
+		return sla
+	}
+
+	sla.HasData = true
+	sla.UptimePercent = float64(up) / float64(sla.Observed) * 100
+	sla.DegradedPercent = float64(degraded) / float64(sla.Observed) * 100
+	if sla.Outages > 0 {
+		sla.MTBF = up / time.Duration(sla.Outages)
+	}
+	if recoveries > 0 {
+		sla.MTTR = recovered / time.Duration(recoveries)
+	}
+	return sla
+}
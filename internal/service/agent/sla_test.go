@@ -0,0 +1,154 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Shemistan/agent/internal/service"
+	"github.com/Shemistan/agent/internal/storage"
+)
+
+// MockSLAStorage implements storage.ManagerSLAStorage interface
+type MockSLAStorage struct {
+	runs   []storage.StatusRun
+	oldest time.Time
+}
+
+func (m *MockSLAStorage) ListStatusRuns(ctx context.Context, managerURL string, from, to time.Time) ([]storage.StatusRun, error) {
+	return m.runs, nil
+}
+
+func (m *MockSLAStorage) OldestManagerCheck(ctx context.Context, managerURL string) (time.Time, error) {
+	return m.oldest, nil
+}
+
+func TestComputeSLA(t *testing.T) {
+	to := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	from := to.Add(-10 * time.Hour)
+	runs := []storage.StatusRun{
+		{Status: "success", StartedAt: from},
+		{Status: "error", StartedAt: from.Add(4 * time.Hour)},   // 1h outage, recovered
+		{Status: "success", StartedAt: from.Add(5 * time.Hour)}, // 3h up
+		{Status: "error", StartedAt: from.Add(8 * time.Hour)},   // 2h outage, ongoing
+	}
+
+	sla := computeSLA(runs, from, to)
+
+	if !sla.HasData || sla.Observed != 10*time.Hour {
+		t.Fatalf("Expected 10h observed, got %v", sla.Observed)
+	}
+	if sla.UptimePercent != 70 {
+		t.Fatalf("Expected 70%% uptime, got %v", sla.UptimePercent)
+	}
+	if sla.Outages != 2 {
+		t.Fatalf("Expected 2 outages, got %d", sla.Outages)
+	}
+	if sla.MTBF != 210*time.Minute {
+		t.Fatalf("Expected MTBF 3.5h, got %v", sla.MTBF)
+	}
+	if sla.MTTR != time.Hour {
+		t.Fatalf("Expected MTTR 1h from the recovered outage only, got %v", sla.MTTR)
+	}
+	if sla.LongestOutage != 2*time.Hour {
+		t.Fatalf("Expected longest outage 2h, got %v", sla.LongestOutage)
+	}
+}
+
+func TestComputeSLA_ClipsRunsToWindow(t *testing.T) {
+	to := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	runs := []storage.StatusRun{
+		{Status: "error", StartedAt: to.Add(-3 * time.Hour)},
+		{Status: "success", StartedAt: to.Add(-30 * time.Minute)},
+	}
+
+	sla := computeSLA(runs, to.Add(-time.Hour), to)
+
+	if sla.Observed != time.Hour {
+		t.Fatalf("Expected 1h observed, got %v", sla.Observed)
+	}
+	if sla.UptimePercent != 50 {
+		t.Fatalf("Expected 50%% uptime, got %v", sla.UptimePercent)
+	}
+	if sla.LongestOutage != 30*time.Minute {
+		t.Fatalf("Expected outage clipped to 30m, got %v", sla.LongestOutage)
+	}
+}
+
+func TestComputeSLA_DegradedCountsAsUp(t *testing.T) {
+	to := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	from := to.Add(-4 * time.Hour)
+	runs := []storage.StatusRun{
+		{Status: "success", StartedAt: from},
+		{Status: "degraded", StartedAt: from.Add(time.Hour)},
+		{Status: "success", StartedAt: from.Add(2 * time.Hour)},
+		{Status: "error", StartedAt: from.Add(3 * time.Hour)},
+	}
+
+	sla := computeSLA(runs, from, to)
+
+	if sla.UptimePercent != 75 || sla.DegradedPercent != 25 {
+		t.Fatalf("Expected 75%% uptime and 25%% degraded, got %v and %v", sla.UptimePercent, sla.DegradedPercent)
+	}
+	if sla.Outages != 1 {
+		t.Fatalf("Expected degraded time not to count as an outage, got %d outages", sla.Outages)
+	}
+}
+
+func TestSLAService_UnknownManager(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	slaService := NewSLAService(&MockSLAStorage{}, []string{"http://m1"}, logger)
+
+	_, err := slaService.ManagerSLA(context.Background(), "http://other")
+	if !errors.Is(err, service.ErrUnknownManager) {
+		t.Fatalf("Expected ErrUnknownManager, got %v", err)
+	}
+
+	report, err := slaService.ManagerSLA(context.Background(), "http://m1")
+	if err != nil {
+		t.Fatalf("ManagerSLA failed: %v", err)
+	}
+	if len(report.Windows) != len(slaWindows) {
+		t.Fatalf("Expected %d windows, got %d", len(slaWindows), len(report.Windows))
+	}
+}
+
+func TestSLAService_ReportsPartialWindows(t *testing.T) {
+	now := time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC)
+	// Raw checks are kept for 7 days; the manager was down for one day and up for the other six
+	oldest := now.Add(-7 * 24 * time.Hour)
+	mock := &MockSLAStorage{
+		oldest: oldest,
+		runs: []storage.StatusRun{
+			{Status: "error", StartedAt: oldest},
+			{Status: "success", StartedAt: oldest.Add(24 * time.Hour)},
+		},
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	slaService := NewSLAService(mock, []string{"http://m1"}, logger)
+	slaService.now = func() time.Time { return now }
+
+	report, err := slaService.ManagerSLA(context.Background(), "http://m1")
+	if err != nil {
+		t.Fatalf("ManagerSLA failed: %v", err)
+	}
+
+	for _, sla := range report.Windows {
+		switch sla.Window {
+		case "24h":
+			if sla.Partial || !sla.From.Equal(now.Add(-24*time.Hour)) {
+				t.Errorf("Expected the 24h window to be complete, got from %v, partial %v", sla.From, sla.Partial)
+			}
+		case "30d":
+			if !sla.Partial || !sla.From.Equal(oldest) {
+				t.Errorf("Expected the 30d window to start at the oldest check %v, got %v, partial %v", oldest, sla.From, sla.Partial)
+			}
+			if sla.Observed != 7*24*time.Hour {
+				t.Errorf("Expected 7 days observed in the 30d window, got %v", sla.Observed)
+			}
+		}
+	}
+}
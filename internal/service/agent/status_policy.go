@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"github.com/Shemistan/agent/internal/service"
+)
+
+// StatusPolicy derives the overall status of a check run from the statuses of individual managers.
+// Under every mode the overall status is success only when all managers are healthy.
+type StatusPolicy struct {
+	Mode string // one of the service.Policy* modes, PolicyAnyDown when empty
+
+	// Quorum is the number of managers that must be up under PolicyQuorum,
+	// a majority of the checked managers when zero
+	Quorum int
+
+	// MinUpWeightPercent is the share of the total weight that managers that are up
+	// must carry under PolicyWeighted; zero accepts any share
+	MinUpWeightPercent int
+}
+
+// overall returns the status of all results; targets supplies weights in the same order
+func (p StatusPolicy) overall(results []service.ManagerCheckResult, targets []service.ManagerTarget) service.Status {
+	var up, upWeight, totalWeight int
+	healthy := true
+	for i, result := range results {
+		weight := 1
+		if i < len(targets) && targets[i].Weight > 0 {
+			weight = targets[i].Weight
+		}
+		totalWeight += weight
+
+		if result.Status != service.StatusSuccess {
+			healthy = false
+		}
+		if result.Status.IsUp() {
+			up++
+			upWeight += weight
+		}
+	}
+
+	var enough bool
+	switch p.Mode {
+	case service.PolicyQuorum:
+		quorum := p.Quorum
+		if quorum <= 0 {
+			quorum = len(results)/2 + 1
+		}
+		enough = up >= quorum
+	case service.PolicyWeighted:
+		enough = upWeight*100 >= totalWeight*p.MinUpWeightPercent
+	default:
+		enough = up == len(results)
+	}
+
+	switch {
+	case !enough:
+		return service.StatusError
+	case !healthy:
+		return service.StatusDegraded
+	default:
+		return service.StatusSuccess
+	}
+}
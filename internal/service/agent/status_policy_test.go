@@ -0,0 +1,51 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/Shemistan/agent/internal/service"
+)
+
+func statusResults(statuses ...service.Status) []service.ManagerCheckResult {
+	results := make([]service.ManagerCheckResult, 0, len(statuses))
+	for _, status := range statuses {
+		results = append(results, service.ManagerCheckResult{Status: status})
+	}
+	return results
+}
+
+func TestStatusPolicy(t *testing.T) {
+	const (
+		ok       = service.StatusSuccess
+		degraded = service.StatusDegraded
+		down     = service.StatusError
+	)
+	weights := []service.ManagerTarget{{Weight: 3}, {Weight: 1}, {Weight: 1}}
+
+	tests := []struct {
+		name     string
+		policy   StatusPolicy
+		statuses []service.Status
+		targets  []service.ManagerTarget
+		expected service.Status
+	}{
+		{"any down healthy", StatusPolicy{}, []service.Status{ok, ok}, nil, ok},
+		{"any down degraded", StatusPolicy{}, []service.Status{ok, degraded}, nil, degraded},
+		{"any down one error", StatusPolicy{}, []service.Status{ok, degraded, down}, nil, down},
+		{"no managers", StatusPolicy{}, nil, nil, ok},
+		{"quorum majority up", StatusPolicy{Mode: service.PolicyQuorum}, []service.Status{ok, ok, down}, nil, degraded},
+		{"quorum lost", StatusPolicy{Mode: service.PolicyQuorum}, []service.Status{ok, down, down}, nil, down},
+		{"quorum explicit", StatusPolicy{Mode: service.PolicyQuorum, Quorum: 1}, []service.Status{degraded, down, down}, nil, degraded},
+		{"quorum above managers up", StatusPolicy{Mode: service.PolicyQuorum, Quorum: 3}, []service.Status{ok, ok}, nil, down},
+		{"weighted heavy up", StatusPolicy{Mode: service.PolicyWeighted, MinUpWeightPercent: 50}, []service.Status{ok, down, down}, weights, degraded},
+		{"weighted heavy down", StatusPolicy{Mode: service.PolicyWeighted, MinUpWeightPercent: 50}, []service.Status{down, ok, ok}, weights, down},
+		{"weighted threshold", StatusPolicy{Mode: service.PolicyWeighted, MinUpWeightPercent: 80}, []service.Status{ok, ok, down}, weights, degraded},
+		{"weighted default weights", StatusPolicy{Mode: service.PolicyWeighted, MinUpWeightPercent: 50}, []service.Status{ok, down}, nil, degraded},
+		{"weighted zero share", StatusPolicy{Mode: service.PolicyWeighted}, []service.Status{down, down}, weights, degraded},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.overall(statusResults(tt.statuses...), tt.targets); got != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.expected, got)
+		}
+	}
+}
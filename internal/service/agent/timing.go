@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/Shemistan/agent/internal/service"
+)
+
+// traceTimer captures the phase timings of a single HTTP request via httptrace.
+// Phases that did not happen, e.g. DNS and connect on a reused connection, stay zero.
+type traceTimer struct {
+	mu        sync.Mutex
+	start     time.Time
+	dnsStart  time.Time
+	dnsDone   time.Time
+	connStart time.Time
+	connDone  time.Time
+	tlsStart  time.Time
+	tlsDone   time.Time
+	firstByte time.Time
+}
+
+func newTraceTimer() *traceTimer {
+	return &traceTimer{start: time.Now()}
+}
+
+// trace returns client hooks that record phase boundaries
+func (t *traceTimer) trace() *httptrace.ClientTrace {
+	record := func(dst *time.Time, first bool) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if first && !dst.IsZero() {
+			return
+		}
+		*dst = time.Now()
+	}
+
+	return &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { record(&t.dnsStart, true) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { record(&t.dnsDone, false) },
+		ConnectStart:         func(string, string) { record(&t.connStart, true) },
+		ConnectDone:          func(string, string, error) { record(&t.connDone, false) },
+		TLSHandshakeStart:    func() { record(&t.tlsStart, true) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { record(&t.tlsDone, false) },
+		GotFirstResponseByte: func() { record(&t.firstByte, true) },
+	}
+}
+
+// timing converts recorded boundaries to phase durations, with total measured up to end
+func (t *traceTimer) timing(end time.Time) service.CheckTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return service.CheckTiming{
+		DNS:          between(t.dnsStart, t.dnsDone),
+		Connect:      between(t.connStart, t.connDone),
+		TLSHandshake: between(t.tlsStart, t.tlsDone),
+		FirstByte:    between(t.start, t.firstByte),
+		Total:        end.Sub(t.start),
+	}
+}
+
+func between(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() {
+		return 0
+	}
+	return end.Sub(start)
+}
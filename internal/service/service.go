@@ -1,26 +1,352 @@
 package service
 
-import "context"
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HealthCall describes a served /health request
+type HealthCall struct {
+	CalledAt  time.Time
+	CallerIP  string // host part of the remote address
+	UserAgent string
+	Latency   time.Duration
+}
 
 // HealthService defines the interface for health check operations
 type HealthService interface {
-	HandleHealth(ctx context.Context) error
+	// RecordHealthCall records the call in the background and never waits for storage
+	RecordHealthCall(call HealthCall)
+}
+
+// Status is the health of a single check or of all managers together
+type Status string
+
+// Check statuses
+const (
+	StatusSuccess  Status = "success"  // healthy
+	StatusDegraded Status = "degraded" // serving, but slow or partially failing
+	StatusError    Status = "error"    // unhealthy
+)
+
+// IsUp reports whether the status counts as available; degraded is up
+func (s Status) IsUp() bool {
+	return s == StatusSuccess || s == StatusDegraded
+}
+
+// IsValid reports whether s is one of the known statuses
+func (s Status) IsValid() bool {
+	return s == StatusSuccess || s == StatusDegraded || s == StatusError
+}
+
+// Overall status policies
+const (
+	PolicyAnyDown  = "any_down" // error if any manager is down, degraded if any is not healthy
+	PolicyQuorum   = "quorum"   // error if fewer than a quorum of managers are up
+	PolicyWeighted = "weighted" // error if managers that are up carry too little of the total weight
+)
+
+// HealthCheckResult represents the outcome of a single named liveness or readiness check
+type HealthCheckResult struct {
+	Name     string
+	Status   Status // StatusSuccess or StatusError
+	Error    string
+	Duration time.Duration
+	Details  map[string]any // check specific figures, e.g. expected and current schema version
+}
+
+// HealthReport represents the outcome of all checks of a probe
+type HealthReport struct {
+	Status Status // StatusError if any check failed
+	Checks []HealthCheckResult
+}
+
+// ProbeService defines the interface for the liveness and readiness probes of the agent itself
+type ProbeService interface {
+	// Liveness reports whether the process is working; failing it means "restart me"
+	Liveness(ctx context.Context) HealthReport
+	// Readiness reports whether the agent can serve traffic; failing it means "don't route to me".
+	// It includes the liveness checks.
+	Readiness(ctx context.Context) HealthReport
+}
+
+// CheckTiming represents the latency breakdown of a manager check.
+// Phases that did not happen, e.g. on a reused connection, are zero.
+type CheckTiming struct {
+	DNS          time.Duration
+	Connect      time.Duration
+	TLSHandshake time.Duration
+	FirstByte    time.Duration // from request start to the first response byte
+	Total        time.Duration // whole check, including retries and backoff
+}
+
+// Probe types
+const (
+	ProbeHTTP     = "http"     // HTTP request with status and JSON body expectations
+	ProbeTCP      = "tcp"      // TCP connect to host:port
+	ProbeTLS      = "tls"      // TLS handshake and certificate validity
+	ProbeDNS      = "dns"      // resolution of a host name
+	ProbeGRPC     = "grpc"     // grpc.health.v1 health checking protocol
+	ProbePostgres = "postgres" // connect and ping a PostgreSQL database
+)
+
+// ManagerTarget describes how to check a single manager or other dependency
+type ManagerTarget struct {
+	Name            string
+	Type            string // one of the Probe* types, ProbeHTTP when empty
+	URL             string // base URL or address; ManagerURL of results and stored checks
+	HealthPath      string // HTTP: appended to URL, "/health" when empty
+	Method          string // HTTP: GET when empty
+	Headers         map[string]string
+	Body            string        // HTTP: request body
+	Timeout         time.Duration // zero uses the service-wide check timeout
+	ExpectedStatus  []int         // HTTP: accepted status codes, 200 when empty
+	ExpectedField   string        // HTTP: JSON path that must equal ExpectedValue, empty skips the check
+	ExpectedValue   string        // also DNS: an address the name must resolve to
+	GRPCService     string        // gRPC: service name to check, empty for the whole server
+	MinCertValidity time.Duration // TLS: fail when the certificate expires sooner
+	ManagerTLS      bool          // HTTPS, TLS and gRPC: use the manager CA, client certificate and server name
+	Assertions      []Assertion   // HTTP: additional expectations on the response
+	MaxBodyBytes    int64         // HTTP: larger responses fail, zero uses a 1 MiB limit
+	MaxLatency      time.Duration // HTTP: slower responses fail, zero disables the limit
+	DegradedLatency time.Duration // HTTP: slower successful responses are degraded, zero disables
+	Weight          int           // share in the weighted overall status, 1 when zero
+	Tags            []string
+}
+
+// Assertion sources
+const (
+	AssertBody   = "body"   // Path is a gjson-style path into the JSON response body
+	AssertHeader = "header" // Path is a response header name
+)
+
+// Assertion operators
+const (
+	OpEquals    = "equals"
+	OpNotEquals = "not_equals"
+	OpContains  = "contains" // substring, array element or object key
+	OpMatches   = "matches"  // regular expression
+	OpExists    = "exists"
+	OpNotExists = "not_exists"
+)
+
+// Assertion describes a single expectation on an HTTP response.
+// Body paths use dots for nesting, numbers for array indexes, "*" for all children
+// (e.g. "components.*" to require every component to be "ok") and "#" for array length.
+type Assertion struct {
+	Source   string // AssertBody when empty
+	Path     string
+	Op       string // OpEquals when empty
+	Value    string
+	Severity Status         // status of the check when the assertion fails, StatusError when empty
+	Pattern  *regexp.Regexp // OpMatches: compiled Value, set when the target is built; nil compiles on every evaluation
+}
+
+// AssertionFailure describes an assertion that did not hold
+type AssertionFailure struct {
+	Source   string
+	Path     string // concrete path of the offending value, e.g. "components.queue"
+	Op       string
+	Expected string
+	Actual   string
+	Missing  bool   // the path or header was not found
+	Severity Status // StatusError or StatusDegraded
+}
+
+// String formats the failure as `<source> <path> <op> "<expected>": got "<actual>"`
+func (f AssertionFailure) String() string {
+	msg := fmt.Sprintf("%s %s %s", f.Source, f.Path, f.Op)
+	if f.Expected != "" {
+		msg += fmt.Sprintf(" %q", f.Expected)
+	}
+	if f.Missing {
+		return msg + ": missing"
+	}
+	return msg + fmt.Sprintf(": got %q", f.Actual)
 }
 
 // ManagerCheckResult represents the result of a single manager health check
 type ManagerCheckResult struct {
+	ManagerName  string
 	ManagerURL   string
-	Status       string // "success" or "error"
+	Status       Status
 	HTTPStatus   int
 	ErrorMessage string
+	TLSVersion   string      // negotiated TLS version, empty for plain HTTP
+	CertNotAfter time.Time   // peer leaf certificate expiry, zero for plain HTTP
+	Timing       CheckTiming // phases of the last attempt
+	Attempts     int         // number of requests made, more than one when transient failures were retried
+
+	FailedAssertions []AssertionFailure // also summarised in ErrorMessage
 }
 
 // ManagerCheckResults represents results from checking multiple managers
 type ManagerCheckResults struct {
+	Status  Status // overall status according to the configured policy
 	Results []ManagerCheckResult
 }
 
+// CheckObserver receives the outcome of every manager check, e.g. to export metrics.
+// It may be called before the check is persisted; consumers of the stored history
+// must use the OnWritten hook of the check writer instead.
+type CheckObserver interface {
+	ObserveManagerCheck(result ManagerCheckResult, duration time.Duration)
+}
+
 // ManagerCheckService defines the interface for manager check operations
 type ManagerCheckService interface {
 	CheckManager(ctx context.Context) (ManagerCheckResults, error)
 }
+
+// SchedulerStatus describes the state of the background manager check scheduler
+type SchedulerStatus struct {
+	Enabled      bool
+	Running      bool // a check run is in progress
+	Interval     time.Duration
+	LastRunAt    time.Time
+	LastDuration time.Duration
+	NextRunAt    time.Time
+	Runs         int64
+	SkippedTicks int64 // ticks skipped because the previous run was still going
+}
+
+// Scheduler defines the interface for inspecting background check scheduling
+type Scheduler interface {
+	Status() SchedulerStatus
+}
+
+// ManagerCheckQuery describes a page of historical manager checks to fetch
+type ManagerCheckQuery struct {
+	ManagerURL string
+	Status     Status // empty means any status
+	From       time.Time
+	To         time.Time
+	Cursor     ManagerCheckCursor // position after the last check of the previous page, zero for the first page
+	Limit      int
+}
+
+// ManagerCheckCursor is a position in the check history, which is ordered by checked_at and then id.
+// It does not refer to a stored row, so paging goes on after the row it was taken from is purged.
+type ManagerCheckCursor struct {
+	CheckedAt time.Time
+	ID        int64
+}
+
+// IsZero reports whether the cursor is the start of the history
+func (c ManagerCheckCursor) IsZero() bool {
+	return c.CheckedAt.IsZero() && c.ID == 0
+}
+
+// String encodes the cursor as an opaque token, empty for the zero cursor
+func (c ManagerCheckCursor) String() string {
+	if c.IsZero() {
+		return ""
+	}
+	raw := strconv.FormatInt(c.CheckedAt.UnixNano(), 10) + "." + strconv.FormatInt(c.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseManagerCheckCursor decodes a token produced by ManagerCheckCursor.String
+func ParseManagerCheckCursor(token string) (ManagerCheckCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return ManagerCheckCursor{}, fmt.Errorf("invalid cursor %q", token)
+	}
+	checkedAt, id, ok := strings.Cut(string(raw), ".")
+	if !ok {
+		return ManagerCheckCursor{}, fmt.Errorf("invalid cursor %q", token)
+	}
+	nanos, err := strconv.ParseInt(checkedAt, 10, 64)
+	if err != nil {
+		return ManagerCheckCursor{}, fmt.Errorf("invalid cursor %q", token)
+	}
+	cursor := ManagerCheckCursor{CheckedAt: time.Unix(0, nanos).UTC()}
+	if cursor.ID, err = strconv.ParseInt(id, 10, 64); err != nil || cursor.ID <= 0 {
+		return ManagerCheckCursor{}, fmt.Errorf("invalid cursor %q", token)
+	}
+	return cursor, nil
+}
+
+// ManagerCheckRecord represents a stored manager check
+type ManagerCheckRecord struct {
+	ID           int64
+	CheckedAt    time.Time
+	ManagerURL   string
+	Status       Status
+	HTTPStatus   *int
+	ErrorMessage *string
+	Timing       CheckTiming
+	Attempts     int
+}
+
+// ManagerCheckPage represents a page of historical manager checks
+type ManagerCheckPage struct {
+	Checks     []ManagerCheckRecord
+	NextCursor ManagerCheckCursor // zero when there are no more pages
+}
+
+// ManagerCheckHistoryService defines the interface for reading manager check history
+type ManagerCheckHistoryService interface {
+	ListManagerChecks(ctx context.Context, query ManagerCheckQuery) (ManagerCheckPage, error)
+}
+
+// ErrUnknownManager is returned when a manager is not part of the configuration
+var ErrUnknownManager = errors.New("unknown manager")
+
+// ManagerSLA represents availability of a manager over one rolling window.
+// Durations are time-weighted: each status lasts until the next differing check.
+type ManagerSLA struct {
+	Window          string // e.g. "24h"
+	From            time.Time
+	To              time.Time
+	Partial         bool // From is later than the start of the window: older raw checks are purged or never existed
+	HasData         bool
+	Observed        time.Duration // time covered by checks within the window
+	UptimePercent   float64       // degraded time counts as up
+	DegradedPercent float64
+	Outages         int           // number of error runs within the window
+	MTBF            time.Duration // mean up time between failures, zero without failures
+	MTTR            time.Duration // mean duration of outages that recovered, zero without recoveries
+	LongestOutage   time.Duration
+}
+
+// ManagerSLAReport represents availability of a manager over all rolling windows
+type ManagerSLAReport struct {
+	ManagerURL string
+	Windows    []ManagerSLA
+}
+
+// SLAService defines the interface for manager availability reporting
+type SLAService interface {
+	ManagerSLA(ctx context.Context, managerURL string) (ManagerSLAReport, error)
+}
+
+// Alert kinds
+const (
+	AlertDown      = "down"       // manager went down after consecutive failures
+	AlertRecovered = "recovered"  // manager is back up after consecutive successes
+	AlertStillDown = "still_down" // periodic reminder while a manager stays down
+)
+
+// AlertEvent represents a manager state transition or reminder to notify about
+type AlertEvent struct {
+	Kind        string
+	ManagerURL  string
+	At          time.Time
+	DownSince   time.Time // when the manager was marked down, zero for unknown
+	Consecutive int       // consecutive checks with the new status
+	LastError   string
+	HTTPStatus  int
+}
+
+// AlertSink delivers alert events to an external system
+type AlertSink interface {
+	Name() string
+	Send(ctx context.Context, event AlertEvent) error
+}
@@ -0,0 +1,265 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Shemistan/agent/internal/storage"
+)
+
+var (
+	// ErrBufferFull is returned when a check could not be buffered within the enqueue timeout
+	ErrBufferFull = errors.New("manager check buffer is full")
+	// ErrWriterStopped is returned for checks saved after the writer was stopped
+	ErrWriterStopped = errors.New("manager check writer is stopped")
+)
+
+// ManagerCheckSpool keeps manager checks that could not be written for a later retry
+type ManagerCheckSpool interface {
+	Append(checks []storage.ManagerCheck) error
+}
+
+// BatchWriterConfig configures buffering of manager checks
+type BatchWriterConfig struct {
+	BufferSize     int           // checks buffered before callers are held back
+	BatchSize      int           // checks written per flush at most
+	FlushInterval  time.Duration // buffered checks are written at least this often
+	FlushTimeout   time.Duration // time limit of one flush
+	EnqueueTimeout time.Duration // how long a caller waits for room in a full buffer
+
+	// Spool, if set, receives checks that failed to be written or did not fit into the buffer
+	Spool ManagerCheckSpool
+
+	// OnWritten, if set, is called from the writer goroutine with every batch once it is stored;
+	// the slice is reused afterwards and must not be retained
+	OnWritten func(checks []storage.ManagerCheck)
+}
+
+// BatchWriter implements ManagerCheckStorage by buffering checks in memory and writing them
+// in batches in the background, so that callers do not wait for the database.
+// When the buffer is full callers are held back for up to EnqueueTimeout.
+type BatchWriter struct {
+	storage storage.ManagerCheckBatchStorage
+	cfg     BatchWriterConfig
+	logger  *slog.Logger
+
+	queue chan storage.ManagerCheck
+
+	// mu guards closing the queue against concurrent sends
+	mu      sync.RWMutex
+	stopped bool
+
+	startOnce   sync.Once
+	flushCtx    context.Context
+	cancelFlush context.CancelFunc
+	loopDone    chan struct{}
+
+	written atomic.Int64
+	spooled atomic.Int64
+	dropped atomic.Int64
+}
+
+// NewBatchWriter creates a new BatchWriter instance
+func NewBatchWriter(batchStorage storage.ManagerCheckBatchStorage, cfg BatchWriterConfig, logger *slog.Logger) *BatchWriter {
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 1000
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.FlushTimeout <= 0 {
+		cfg.FlushTimeout = 10 * time.Second
+	}
+	if cfg.EnqueueTimeout <= 0 {
+		cfg.EnqueueTimeout = time.Second
+	}
+
+	flushCtx, cancelFlush := context.WithCancel(context.Background())
+	return &BatchWriter{
+		storage:     batchStorage,
+		cfg:         cfg,
+		logger:      logger,
+		queue:       make(chan storage.ManagerCheck, cfg.BufferSize),
+		flushCtx:    flushCtx,
+		cancelFlush: cancelFlush,
+		loopDone:    make(chan struct{}),
+	}
+}
+
+// Start starts writing buffered checks in the background
+func (w *BatchWriter) Start() {
+	w.startOnce.Do(func() {
+		go w.loop()
+		w.logger.Info("batch writer: started",
+			slog.Int("buffer_size", w.cfg.BufferSize),
+			slog.Int("batch_size", w.cfg.BatchSize),
+			slog.Duration("flush_interval", w.cfg.FlushInterval),
+		)
+	})
+}
+
+// SaveManagerCheck buffers the check for writing. It returns once the check is buffered,
+// not when it is stored; a full buffer holds the caller back until there is room,
+// the enqueue timeout expires or ctx is done, and then the check is spooled if possible.
+// Checks without a UID get a random one.
+func (w *BatchWriter) SaveManagerCheck(ctx context.Context, check storage.ManagerCheck) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.stopped {
+		return ErrWriterStopped
+	}
+
+	if check.UID == "" {
+		uid, err := newCheckUID()
+		if err != nil {
+			return err
+		}
+		check.UID = uid
+	}
+
+	select {
+	case w.queue <- check:
+		return nil
+	default:
+	}
+
+	timer := time.NewTimer(w.cfg.EnqueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case w.queue <- check:
+		return nil
+	case <-timer.C:
+		return w.spool([]storage.ManagerCheck{check}, ErrBufferFull)
+	case <-ctx.Done():
+		return w.spool([]storage.ManagerCheck{check}, ctx.Err())
+	}
+}
+
+// spool hands checks that were not written because of cause to the spool.
+// It returns cause, or nil when the checks were spooled.
+func (w *BatchWriter) spool(checks []storage.ManagerCheck, cause error) error {
+	if w.cfg.Spool == nil {
+		w.dropped.Add(int64(len(checks)))
+		return cause
+	}
+	if err := w.cfg.Spool.Append(checks); err != nil {
+		w.dropped.Add(int64(len(checks)))
+		w.logger.Error("batch writer: failed to spool manager checks",
+			slog.Int("checks", len(checks)),
+			slog.String("error", err.Error()),
+		)
+		return cause
+	}
+	w.spooled.Add(int64(len(checks)))
+	return nil
+}
+
+// Stop stops accepting checks and waits until all buffered checks are written.
+// If ctx expires first, the in-flight flush is cancelled and the remaining checks are lost.
+func (w *BatchWriter) Stop(ctx context.Context) {
+	w.mu.Lock()
+	if w.stopped {
+		w.mu.Unlock()
+		return
+	}
+	w.stopped = true
+	close(w.queue)
+	w.mu.Unlock()
+
+	// Drain the buffer even if the writer was never started
+	w.Start()
+
+	select {
+	case <-w.loopDone:
+	case <-ctx.Done():
+		w.logger.Warn("batch writer: grace period expired, abandoning buffered checks")
+		w.cancelFlush()
+		<-w.loopDone
+	}
+	w.cancelFlush()
+
+	w.logger.Info("batch writer: stopped",
+		slog.Int64("written", w.written.Load()),
+		slog.Int64("spooled", w.spooled.Load()),
+		slog.Int64("dropped", w.dropped.Load()),
+	)
+}
+
+// Buffered returns the number of checks waiting to be written
+func (w *BatchWriter) Buffered() int {
+	return len(w.queue)
+}
+
+func (w *BatchWriter) loop() {
+	defer close(w.loopDone)
+
+	ticker := time.NewTicker(w.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]storage.ManagerCheck, 0, w.cfg.BatchSize)
+	for {
+		select {
+		case check, ok := <-w.queue:
+			if !ok {
+				w.flush(batch)
+				return
+			}
+			batch = append(batch, check)
+			if len(batch) >= w.cfg.BatchSize {
+				w.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				w.flush(batch)
+				batch = batch[:0]
+			}
+		}
+	}
+}
+
+// flush writes one batch; a batch that fails is spooled for a later retry
+func (w *BatchWriter) flush(batch []storage.ManagerCheck) {
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(w.flushCtx, w.cfg.FlushTimeout)
+	defer cancel()
+
+	if err := w.storage.SaveManagerChecks(ctx, batch); err != nil {
+		w.logger.Error("batch writer: failed to write manager checks",
+			slog.Int("checks", len(batch)),
+			slog.Bool("spooled", w.cfg.Spool != nil),
+			slog.String("error", err.Error()),
+		)
+		_ = w.spool(batch, err)
+		return
+	}
+	w.written.Add(int64(len(batch)))
+	if w.cfg.OnWritten != nil {
+		w.cfg.OnWritten(batch)
+	}
+}
+
+// newCheckUID returns a random version 4 UUID
+func newCheckUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("generate check uid: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
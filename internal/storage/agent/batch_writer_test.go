@@ -0,0 +1,151 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Shemistan/agent/internal/storage"
+)
+
+// MockBatchStorage implements storage.ManagerCheckBatchStorage interface
+type MockBatchStorage struct {
+	mu      sync.Mutex
+	batches [][]storage.ManagerCheck
+	block   chan struct{} // if set, writes wait until it is closed
+}
+
+func (m *MockBatchStorage) SaveManagerChecks(ctx context.Context, checks []storage.ManagerCheck) error {
+	if m.block != nil {
+		select {
+		case <-m.block:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.batches = append(m.batches, append([]storage.ManagerCheck(nil), checks...))
+	return nil
+}
+
+func (m *MockBatchStorage) saved() (batches, checks int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, batch := range m.batches {
+		checks += len(batch)
+	}
+	return len(m.batches), checks
+}
+
+func newTestWriter(mock *MockBatchStorage, cfg BatchWriterConfig) *BatchWriter {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	return NewBatchWriter(mock, cfg, logger)
+}
+
+func TestBatchWriter_FlushesOnBatchSize(t *testing.T) {
+	mock := &MockBatchStorage{}
+	writer := newTestWriter(mock, BatchWriterConfig{BatchSize: 3, FlushInterval: time.Hour})
+	writer.Start()
+	defer writer.Stop(context.Background())
+
+	for i := 0; i < 7; i++ {
+		if err := writer.SaveManagerCheck(context.Background(), storage.ManagerCheck{ManagerURL: "http://manager"}); err != nil {
+			t.Fatalf("Expected check to be buffered, got %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if batches, checks := mock.saved(); batches == 2 && checks == 6 {
+			break
+		}
+		if time.Now().After(deadline) {
+			batches, checks := mock.saved()
+			t.Fatalf("Expected 2 full batches, got %d batches with %d checks", batches, checks)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestBatchWriter_FlushesOnInterval(t *testing.T) {
+	mock := &MockBatchStorage{}
+	writer := newTestWriter(mock, BatchWriterConfig{BatchSize: 100, FlushInterval: 20 * time.Millisecond})
+	writer.Start()
+	defer writer.Stop(context.Background())
+
+	if err := writer.SaveManagerCheck(context.Background(), storage.ManagerCheck{ManagerURL: "http://manager"}); err != nil {
+		t.Fatalf("Expected check to be buffered, got %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if _, checks := mock.saved(); checks != 1 {
+		t.Errorf("Expected the check to be written after the interval, got %d", checks)
+	}
+}
+
+func TestBatchWriter_BackPressureWhenFull(t *testing.T) {
+	mock := &MockBatchStorage{block: make(chan struct{})}
+	writer := newTestWriter(mock, BatchWriterConfig{
+		BufferSize:     2,
+		BatchSize:      1,
+		FlushInterval:  time.Hour,
+		EnqueueTimeout: 20 * time.Millisecond,
+	})
+	writer.Start()
+
+	// The first check is taken by the blocked flush, the next two fill the buffer
+	for i := 0; i < 3; i++ {
+		if err := writer.SaveManagerCheck(context.Background(), storage.ManagerCheck{}); err != nil {
+			t.Fatalf("Check %d: expected to be buffered, got %v", i, err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	start := time.Now()
+	err := writer.SaveManagerCheck(context.Background(), storage.ManagerCheck{})
+	if !errors.Is(err, ErrBufferFull) {
+		t.Fatalf("Expected ErrBufferFull, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("Expected caller to be held back for the enqueue timeout, returned after %v", elapsed)
+	}
+
+	close(mock.block)
+	writer.Stop(context.Background())
+	if _, checks := mock.saved(); checks != 3 {
+		t.Errorf("Expected all buffered checks to be written, got %d", checks)
+	}
+}
+
+func TestBatchWriter_StopFlushesBuffer(t *testing.T) {
+	mock := &MockBatchStorage{}
+	var written int
+	writer := newTestWriter(mock, BatchWriterConfig{
+		BatchSize:     100,
+		FlushInterval: time.Hour,
+		OnWritten:     func(checks []storage.ManagerCheck) { written += len(checks) },
+	})
+	writer.Start()
+
+	for i := 0; i < 5; i++ {
+		if err := writer.SaveManagerCheck(context.Background(), storage.ManagerCheck{}); err != nil {
+			t.Fatalf("Expected check to be buffered, got %v", err)
+		}
+	}
+	writer.Stop(context.Background())
+
+	if batches, checks := mock.saved(); batches != 1 || checks != 5 {
+		t.Errorf("Expected one batch of 5 checks on stop, got %d batches with %d checks", batches, checks)
+	}
+	if written != 5 {
+		t.Errorf("Expected OnWritten to see 5 checks, got %d", written)
+	}
+	if err := writer.SaveManagerCheck(context.Background(), storage.ManagerCheck{}); !errors.Is(err, ErrWriterStopped) {
+		t.Errorf("Expected ErrWriterStopped after stop, got %v", err)
+	}
+}
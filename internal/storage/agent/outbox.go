@@ -0,0 +1,370 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Shemistan/agent/internal/storage"
+	"github.com/lib/pq"
+)
+
+// outboxRecord is the on-disk form of a spooled manager check, one JSON object per line
+type outboxRecord struct {
+	UID           string    `json:"uid"`
+	CheckedAt     time.Time `json:"checked_at"`
+	ManagerURL    string    `json:"manager_url"`
+	Status        string    `json:"status"`
+	HTTPStatus    *int      `json:"http_status,omitempty"`
+	ErrorMessage  *string   `json:"error_message,omitempty"`
+	DNSMillis     *float64  `json:"dns_ms,omitempty"`
+	ConnectMillis *float64  `json:"connect_ms,omitempty"`
+	TLSMillis     *float64  `json:"tls_ms,omitempty"`
+	TTFBMillis    *float64  `json:"ttfb_ms,omitempty"`
+	TotalMillis   *float64  `json:"total_ms,omitempty"`
+	Attempts      int       `json:"attempts"`
+}
+
+func newOutboxRecord(check storage.ManagerCheck) outboxRecord {
+	return outboxRecord{
+		UID:           check.UID,
+		CheckedAt:     check.CheckedAt,
+		ManagerURL:    check.ManagerURL,
+		Status:        check.Status,
+		HTTPStatus:    check.HTTPStatus,
+		ErrorMessage:  check.ErrorMessage,
+		DNSMillis:     check.DNSMillis,
+		ConnectMillis: check.ConnectMillis,
+		TLSMillis:     check.TLSMillis,
+		TTFBMillis:    check.TTFBMillis,
+		TotalMillis:   check.TotalMillis,
+		Attempts:      check.Attempts,
+	}
+}
+
+func (r outboxRecord) check() storage.ManagerCheck {
+	return storage.ManagerCheck{
+		UID:           r.UID,
+		CheckedAt:     r.CheckedAt,
+		ManagerURL:    r.ManagerURL,
+		Status:        r.Status,
+		HTTPStatus:    r.HTTPStatus,
+		ErrorMessage:  r.ErrorMessage,
+		DNSMillis:     r.DNSMillis,
+		ConnectMillis: r.ConnectMillis,
+		TLSMillis:     r.TLSMillis,
+		TTFBMillis:    r.TTFBMillis,
+		TotalMillis:   r.TotalMillis,
+		Attempts:      r.Attempts,
+	}
+}
+
+// OutboxConfig configures the local outbox of manager checks
+type OutboxConfig struct {
+	Path           string        // append-only JSON Lines file
+	ReplayInterval time.Duration // how often spooled checks are pushed to the database
+	BatchSize      int           // checks written per replayed INSERT
+
+	// OnWritten, if set, is called with every replayed batch once it is stored;
+	// the slice is reused afterwards and must not be retained
+	OnWritten func(checks []storage.ManagerCheck)
+}
+
+// Outbox spools manager checks that could not be written to the database into an append-only
+// file and replays them in the background once the database is reachable again.
+// Replays are idempotent: checks carry a UID and already stored ones are skipped.
+type Outbox struct {
+	storage storage.ManagerCheckBatchStorage
+	cfg     OutboxConfig
+	logger  *slog.Logger
+
+	// mu serializes appends with moving the file away for a replay
+	mu sync.Mutex
+	// replayMu serializes replays
+	replayMu sync.Mutex
+
+	loopMu   sync.Mutex
+	stopLoop context.CancelFunc
+	loopDone chan struct{}
+}
+
+// NewOutbox creates a new Outbox instance and the directory of its file
+func NewOutbox(batchStorage storage.ManagerCheckBatchStorage, cfg OutboxConfig, logger *slog.Logger) (*Outbox, error) {
+	if cfg.ReplayInterval <= 0 {
+		cfg.ReplayInterval = 30 * time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("create outbox directory: %w", err)
+	}
+	return &Outbox{
+		storage: batchStorage,
+		cfg:     cfg,
+		logger:  logger,
+	}, nil
+}
+
+// replayPath is where spooled checks are moved while they are replayed
+func (o *Outbox) replayPath() string {
+	return o.cfg.Path + ".replay"
+}
+
+// rejectedPath is where checks the database refuses to store are kept for inspection
+func (o *Outbox) rejectedPath() string {
+	return o.cfg.Path + ".rejected"
+}
+
+// Append spools checks to the outbox file and syncs it to disk
+func (o *Outbox) Append(checks []storage.ManagerCheck) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return appendRecords(o.cfg.Path, checks)
+}
+
+// appendRecords appends checks to the JSON Lines file at path and syncs it to disk
+func appendRecords(path string, checks []storage.ManagerCheck) error {
+	if len(checks) == 0 {
+		return nil
+	}
+
+	var buf []byte
+	for _, check := range checks {
+		line, err := json.Marshal(newOutboxRecord(check))
+		if err != nil {
+			return fmt.Errorf("encode outbox record: %w", err)
+		}
+		buf = append(buf, line...)
+		buf = append(buf, '\n')
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open outbox: %w", err)
+	}
+	if _, err := file.Write(buf); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("append to outbox: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("sync outbox: %w", err)
+	}
+	return file.Close()
+}
+
+// Start replays spooled checks immediately and then every interval in the background
+func (o *Outbox) Start(ctx context.Context) {
+	o.loopMu.Lock()
+	defer o.loopMu.Unlock()
+
+	if o.stopLoop != nil {
+		return
+	}
+
+	loopCtx, stopLoop := context.WithCancel(ctx)
+	o.stopLoop = stopLoop
+	o.loopDone = make(chan struct{})
+
+	go o.loop(loopCtx)
+
+	o.logger.Info("outbox: started",
+		slog.String("path", o.cfg.Path),
+		slog.Duration("replay_interval", o.cfg.ReplayInterval),
+	)
+}
+
+// Stop cancels an in-flight replay and waits for the loop to exit.
+// Checks that were not replayed stay on disk for the next start.
+func (o *Outbox) Stop() {
+	o.loopMu.Lock()
+	stopLoop, loopDone := o.stopLoop, o.loopDone
+	o.loopMu.Unlock()
+
+	if stopLoop == nil {
+		return
+	}
+
+	stopLoop()
+	<-loopDone
+	o.logger.Info("outbox: stopped")
+}
+
+func (o *Outbox) loop(ctx context.Context) {
+	defer close(o.loopDone)
+
+	ticker := time.NewTicker(o.cfg.ReplayInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := o.Replay(ctx); err != nil && ctx.Err() == nil {
+			o.logger.Warn("outbox: replay failed, will retry", slog.String("error", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Replay writes all spooled checks to the database and returns how many were replayed.
+// The outbox file is moved aside first, so appends during the replay go to a new file.
+// If a batch fails, the moved file is kept and replayed from the start next time;
+// batches that were already written are skipped by the database.
+// A batch the database rejects for its data is retried check by check, and checks that
+// are still rejected are moved to the rejected file so that they cannot block the outbox.
+func (o *Outbox) Replay(ctx context.Context) (int, error) {
+	o.replayMu.Lock()
+	defer o.replayMu.Unlock()
+
+	if err := o.takeForReplay(); err != nil {
+		return 0, err
+	}
+
+	file, err := os.Open(o.replayPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("open outbox replay: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	replayed := 0
+	batch := make([]storage.ManagerCheck, 0, o.cfg.BatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := o.storage.SaveManagerChecks(ctx, batch)
+		if err == nil {
+			o.written(batch)
+			replayed += len(batch)
+			batch = batch[:0]
+			return nil
+		}
+		if !isRejected(err) {
+			return err
+		}
+
+		// Checks that are stored are compacted to the front of the batch
+		saved := batch[:0]
+		for _, check := range batch {
+			err := o.storage.SaveManagerChecks(ctx, []storage.ManagerCheck{check})
+			if err == nil {
+				saved = append(saved, check)
+				continue
+			}
+			if !isRejected(err) {
+				o.written(saved)
+				return err
+			}
+			if err := o.reject(check, err); err != nil {
+				o.written(saved)
+				return err
+			}
+		}
+		o.written(saved)
+		replayed += len(saved)
+		batch = batch[:0]
+		return nil
+	}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var record outboxRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			// A torn last line after a crash must not block the rest of the outbox
+			o.logger.Warn("outbox: skipping unreadable record", slog.Int("line", line), slog.String("error", err.Error()))
+			continue
+		}
+		batch = append(batch, record.check())
+		if len(batch) >= o.cfg.BatchSize {
+			if err := flush(); err != nil {
+				return replayed, fmt.Errorf("replay outbox: %w", err)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return replayed, fmt.Errorf("read outbox replay: %w", err)
+	}
+	if err := flush(); err != nil {
+		return replayed, fmt.Errorf("replay outbox: %w", err)
+	}
+
+	if err := os.Remove(o.replayPath()); err != nil {
+		return replayed, fmt.Errorf("remove replayed outbox: %w", err)
+	}
+	if replayed > 0 {
+		o.logger.Info("outbox: replayed spooled checks", slog.Int("checks", replayed))
+	}
+	return replayed, nil
+}
+
+// takeForReplay moves the outbox file aside unless an earlier, unfinished replay is still pending
+func (o *Outbox) takeForReplay() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if _, err := os.Stat(o.replayPath()); err == nil {
+		return nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("stat outbox replay: %w", err)
+	}
+
+	if err := os.Rename(o.cfg.Path, o.replayPath()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("move outbox for replay: %w", err)
+	}
+	return nil
+}
+
+// written passes stored checks to OnWritten
+func (o *Outbox) written(checks []storage.ManagerCheck) {
+	if o.cfg.OnWritten != nil && len(checks) > 0 {
+		o.cfg.OnWritten(checks)
+	}
+}
+
+// reject moves a check the database refuses to store to the rejected file
+func (o *Outbox) reject(check storage.ManagerCheck, cause error) error {
+	o.logger.Warn("outbox: database rejected spooled check, moving it aside",
+		slog.String("uid", check.UID),
+		slog.String("path", o.rejectedPath()),
+		slog.String("error", cause.Error()),
+	)
+	if err := appendRecords(o.rejectedPath(), []storage.ManagerCheck{check}); err != nil {
+		return fmt.Errorf("move rejected check: %w", err)
+	}
+	return nil
+}
+
+// isRejected reports whether err means that the database refused the data itself
+// (data exception or integrity constraint violation), so that retrying cannot help.
+// Connection and server errors are retried with the whole outbox later.
+func isRejected(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	switch pqErr.Code.Class() {
+	case "22", "23":
+		return true
+	default:
+		return false
+	}
+}
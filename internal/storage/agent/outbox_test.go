@@ -0,0 +1,264 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Shemistan/agent/internal/storage"
+	"github.com/lib/pq"
+)
+
+// FailingBatchStorage implements storage.ManagerCheckBatchStorage interface and fails every write
+type FailingBatchStorage struct {
+	writes int
+}
+
+func (f *FailingBatchStorage) SaveManagerChecks(ctx context.Context, checks []storage.ManagerCheck) error {
+	f.writes++
+	return errors.New("connection refused")
+}
+
+// RejectingBatchStorage implements storage.ManagerCheckBatchStorage interface and refuses
+// every batch containing the check with the rejected UID, like Postgres does for invalid data
+type RejectingBatchStorage struct {
+	MockBatchStorage
+	rejected string
+}
+
+func (r *RejectingBatchStorage) SaveManagerChecks(ctx context.Context, checks []storage.ManagerCheck) error {
+	for _, check := range checks {
+		if check.UID == r.rejected {
+			return &pq.Error{Code: "22021", Message: "invalid byte sequence for encoding \"UTF8\": 0x00"}
+		}
+	}
+	return r.MockBatchStorage.SaveManagerChecks(ctx, checks)
+}
+
+func newTestOutbox(t *testing.T, batchStorage storage.ManagerCheckBatchStorage) *Outbox {
+	t.Helper()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	outbox, err := NewOutbox(batchStorage, OutboxConfig{
+		Path:      filepath.Join(t.TempDir(), "outbox", "manager_checks.jsonl"),
+		BatchSize: 2,
+	}, logger)
+	if err != nil {
+		t.Fatalf("NewOutbox failed: %v", err)
+	}
+	return outbox
+}
+
+func spooledCheck(uid string) storage.ManagerCheck {
+	httpStatus := 503
+	total := 12.5
+	return storage.ManagerCheck{
+		UID:         uid,
+		CheckedAt:   time.Date(2025, 3, 10, 14, 25, 0, 0, time.UTC),
+		ManagerURL:  "http://manager",
+		Status:      "error",
+		HTTPStatus:  &httpStatus,
+		TotalMillis: &total,
+		Attempts:    2,
+	}
+}
+
+func TestOutbox_ReplaysSpooledChecks(t *testing.T) {
+	mock := &MockBatchStorage{}
+	outbox := newTestOutbox(t, mock)
+
+	checks := []storage.ManagerCheck{spooledCheck("a"), spooledCheck("b"), spooledCheck("c")}
+	if err := outbox.Append(checks); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	replayed, err := outbox.Replay(context.Background())
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if replayed != 3 {
+		t.Errorf("Expected 3 replayed checks, got %d", replayed)
+	}
+	if batches, _ := mock.saved(); batches != 2 {
+		t.Errorf("Expected 2 batches of at most 2 checks, got %d", batches)
+	}
+
+	got := mock.batches[0][0]
+	if got.UID != "a" || !got.CheckedAt.Equal(checks[0].CheckedAt) || *got.HTTPStatus != 503 ||
+		*got.TotalMillis != 12.5 || got.Attempts != 2 || got.DNSMillis != nil {
+		t.Errorf("Expected the spooled check to round-trip, got %+v", got)
+	}
+
+	// Everything was replayed, so nothing is written again
+	if replayed, err := outbox.Replay(context.Background()); err != nil || replayed != 0 {
+		t.Errorf("Expected an empty outbox, replayed %d, error %v", replayed, err)
+	}
+}
+
+func TestOutbox_KeepsChecksWhileDatabaseIsDown(t *testing.T) {
+	failing := &FailingBatchStorage{}
+	outbox := newTestOutbox(t, failing)
+
+	if err := outbox.Append([]storage.ManagerCheck{spooledCheck("a")}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if _, err := outbox.Replay(context.Background()); err == nil {
+		t.Fatal("Expected replay to fail while the database is down")
+	}
+
+	// Checks spooled during the outage are kept next to the pending replay
+	if err := outbox.Append([]storage.ManagerCheck{spooledCheck("b")}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	mock := &MockBatchStorage{}
+	outbox.storage = mock
+	total := 0
+	for i := 0; i < 2; i++ {
+		replayed, err := outbox.Replay(context.Background())
+		if err != nil {
+			t.Fatalf("Replay failed: %v", err)
+		}
+		total += replayed
+	}
+	if total != 2 {
+		t.Errorf("Expected both checks to be replayed once the database is back, got %d", total)
+	}
+}
+
+func TestOutbox_SkipsTornRecord(t *testing.T) {
+	mock := &MockBatchStorage{}
+	outbox := newTestOutbox(t, mock)
+
+	if err := outbox.Append([]storage.ManagerCheck{spooledCheck("a")}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	file, err := os.OpenFile(outbox.cfg.Path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("Failed to open outbox: %v", err)
+	}
+	if _, err := file.WriteString(`{"uid":"b","checked_at":"2025-03`); err != nil {
+		t.Fatalf("Failed to write torn record: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatalf("Failed to close outbox: %v", err)
+	}
+
+	replayed, err := outbox.Replay(context.Background())
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if replayed != 1 {
+		t.Errorf("Expected the readable check to be replayed, got %d", replayed)
+	}
+}
+
+func TestOutbox_MovesRejectedCheckAside(t *testing.T) {
+	rejecting := &RejectingBatchStorage{rejected: "b"}
+	outbox := newTestOutbox(t, rejecting)
+
+	checks := []storage.ManagerCheck{spooledCheck("a"), spooledCheck("b"), spooledCheck("c"), spooledCheck("d")}
+	if err := outbox.Append(checks); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	replayed, err := outbox.Replay(context.Background())
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if replayed != 3 {
+		t.Errorf("Expected the checks around the rejected one to be replayed, got %d", replayed)
+	}
+
+	uids := map[string]bool{}
+	for _, batch := range rejecting.batches {
+		for _, check := range batch {
+			uids[check.UID] = true
+		}
+	}
+	if !uids["a"] || uids["b"] || !uids["c"] || !uids["d"] {
+		t.Errorf("Expected a, c and d to reach the database, got %v", uids)
+	}
+
+	if _, err := os.Stat(outbox.replayPath()); !os.IsNotExist(err) {
+		t.Errorf("Expected the replay file to be removed, got %v", err)
+	}
+	rejected, err := os.ReadFile(outbox.rejectedPath())
+	if err != nil {
+		t.Fatalf("Expected the rejected check to be kept: %v", err)
+	}
+	if !strings.Contains(string(rejected), `"uid":"b"`) || strings.Count(string(rejected), "\n") != 1 {
+		t.Errorf("Expected only the rejected check in %s, got %q", outbox.rejectedPath(), rejected)
+	}
+
+	// The rejected check no longer blocks later replays
+	if err := outbox.Append([]storage.ManagerCheck{spooledCheck("e")}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if replayed, err := outbox.Replay(context.Background()); err != nil || replayed != 1 {
+		t.Errorf("Expected the next check to be replayed, replayed %d, error %v", replayed, err)
+	}
+}
+
+func TestOutbox_NotifiesReplayedChecks(t *testing.T) {
+	rejecting := &RejectingBatchStorage{rejected: "c"}
+	outbox := newTestOutbox(t, rejecting)
+	var notified []string
+	outbox.cfg.OnWritten = func(checks []storage.ManagerCheck) {
+		for _, check := range checks {
+			notified = append(notified, check.UID)
+		}
+	}
+
+	checks := []storage.ManagerCheck{spooledCheck("a"), spooledCheck("b"), spooledCheck("c"), spooledCheck("d")}
+	if err := outbox.Append(checks); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if _, err := outbox.Replay(context.Background()); err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+
+	// Alerting is fed with every stored check, including those stored one by one, but not the rejected one
+	if strings.Join(notified, ",") != "a,b,d" {
+		t.Errorf("Expected replayed checks a, b and d to be passed on, got %v", notified)
+	}
+}
+
+func TestBatchWriter_SpoolsFailedBatch(t *testing.T) {
+	failing := &FailingBatchStorage{}
+	outbox := newTestOutbox(t, failing)
+	writer := newTestWriter(&MockBatchStorage{}, BatchWriterConfig{BatchSize: 10, FlushInterval: time.Hour, Spool: outbox})
+	writer.storage = failing
+	writer.Start()
+
+	for i := 0; i < 3; i++ {
+		if err := writer.SaveManagerCheck(context.Background(), storage.ManagerCheck{ManagerURL: "http://manager"}); err != nil {
+			t.Fatalf("Expected check to be buffered, got %v", err)
+		}
+	}
+	writer.Stop(context.Background())
+
+	mock := &MockBatchStorage{}
+	outbox.storage = mock
+	replayed, err := outbox.Replay(context.Background())
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if replayed != 3 {
+		t.Fatalf("Expected the failed batch to be spooled and replayed, got %d", replayed)
+	}
+	uids := map[string]bool{}
+	for _, batch := range mock.batches {
+		for _, check := range batch {
+			uids[check.UID] = true
+		}
+	}
+	if len(uids) != 3 || uids[""] {
+		t.Errorf("Expected every check to carry its own UID, got %v", uids)
+	}
+}
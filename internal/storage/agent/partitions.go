@@ -0,0 +1,146 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Shemistan/agent/internal/storage"
+)
+
+const (
+	// legacyPartition holds the rows of manager_checks from before partitioning, see migration 010
+	legacyPartition = "manager_checks_legacy"
+	// partitionPrefix and partitionMonthLayout name monthly partitions, e.g. manager_checks_p202601
+	partitionPrefix      = "manager_checks_p"
+	partitionMonthLayout = "200601"
+)
+
+// monthlyPartition matches the names of partitions created by CreatePartition
+var monthlyPartition = regexp.MustCompile(`^manager_checks_p\d{6}$`)
+
+// ListPartitions returns the range partitions of manager_checks ordered by From.
+// Ranges are derived from partition names; the legacy partition ends where the first monthly one starts.
+func (s *Storage) ListPartitions(ctx context.Context) ([]storage.Partition, error) {
+	query := `
+		SELECT c.relname
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = to_regclass('manager_checks')
+	`
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		s.logger.Error("failed to list partitions", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("list partitions: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		partitions []storage.Partition
+		hasLegacy  bool
+	)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scan partition: %w", err)
+		}
+		switch {
+		case name == legacyPartition:
+			hasLegacy = true
+		case monthlyPartition.MatchString(name):
+			from, err := time.Parse(partitionMonthLayout, strings.TrimPrefix(name, partitionPrefix))
+			if err != nil {
+				return nil, fmt.Errorf("parse partition %s: %w", name, err)
+			}
+			partitions = append(partitions, storage.Partition{Name: name, From: from, To: from.AddDate(0, 1, 0)})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list partitions: %w", err)
+	}
+
+	sort.Slice(partitions, func(i, j int) bool {
+		return partitions[i].From.Before(partitions[j].From)
+	})
+	if hasLegacy && len(partitions) > 0 {
+		legacy := storage.Partition{Name: legacyPartition, To: partitions[0].From}
+		partitions = append([]storage.Partition{legacy}, partitions...)
+	}
+	return partitions, nil
+}
+
+// CreatePartition creates the partition of the UTC month starting at from. Rows of that month
+// that already landed in the default partition are moved into it in the same transaction,
+// otherwise attaching would fail.
+func (s *Storage) CreatePartition(ctx context.Context, from time.Time) error {
+	from = from.UTC()
+	from = time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	name := partitionPrefix + from.Format(partitionMonthLayout)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("create partition %s: begin: %w", name, err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	statements := []struct {
+		query string
+		args  []any
+	}{
+		{query: fmt.Sprintf(
+			`CREATE TABLE %s (LIKE manager_checks INCLUDING DEFAULTS INCLUDING CONSTRAINTS)`, name,
+		)},
+		{query: fmt.Sprintf(`
+			WITH moved AS (
+				DELETE FROM manager_checks_default
+				WHERE checked_at >= $1 AND checked_at < $2
+				RETURNING *
+			)
+			INSERT INTO %s SELECT * FROM moved
+		`, name), args: []any{from, to}},
+		{query: fmt.Sprintf(
+			`ALTER TABLE manager_checks ATTACH PARTITION %s FOR VALUES FROM ('%s') TO ('%s')`,
+			name, from.Format(time.RFC3339), to.Format(time.RFC3339),
+		)},
+	}
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt.query, stmt.args...); err != nil {
+			s.logger.Error("failed to create partition", slog.String("partition", name), slog.String("error", err.Error()))
+			return fmt.Errorf("create partition %s: %w", name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("create partition %s: commit: %w", name, err)
+	}
+	return nil
+}
+
+// RemovePartition detaches the named partition of manager_checks and drops it unless detachOnly is set.
+// Only monthly and legacy partitions can be removed.
+func (s *Storage) RemovePartition(ctx context.Context, name string, detachOnly bool) error {
+	if name != legacyPartition && !monthlyPartition.MatchString(name) {
+		return fmt.Errorf("remove partition: %q is not a manager_checks partition", name)
+	}
+
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE manager_checks DETACH PARTITION %s`, name)); err != nil {
+		s.logger.Error("failed to detach partition", slog.String("partition", name), slog.String("error", err.Error()))
+		return fmt.Errorf("detach partition %s: %w", name, err)
+	}
+	if detachOnly {
+		return nil
+	}
+
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf(`DROP TABLE %s`, name)); err != nil {
+		s.logger.Error("failed to drop partition", slog.String("partition", name), slog.String("error", err.Error()))
+		return fmt.Errorf("drop partition %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package agent
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Shemistan/agent/internal/storage"
+)
+
+// rollupSource describes a raw table that is downsampled and purged
+type rollupSource struct {
+	timeColumn string
+	// aggregate selects rollup rows from raw rows within [$1, $2) bucketed by date_trunc($3);
+	// %[1]s is the rollup table
+	aggregate string
+}
+
+// rollupSources lists the raw tables known to the retention subsystem.
+// Buckets are truncated in UTC so that daily rollups do not depend on the session time zone.
+var rollupSources = map[string]rollupSource{
+	storage.RollupManagerChecks: {
+		timeColumn: "checked_at",
+		aggregate: `
+			INSERT INTO %[1]s (
+				bucket, manager_url, checks, successes, degraded, errors, success_ratio, p50_ms, p95_ms
+			)
+			SELECT
+				date_trunc($3, checked_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC',
+				manager_url,
+				COUNT(*),
+				COUNT(*) FILTER (WHERE status = 'success'),
+				COUNT(*) FILTER (WHERE status = 'degraded'),
+				COUNT(*) FILTER (WHERE status = 'error'),
+				COUNT(*) FILTER (WHERE status = 'success')::DOUBLE PRECISION / COUNT(*),
+				percentile_cont(0.5) WITHIN GROUP (ORDER BY total_ms),
+				percentile_cont(0.95) WITHIN GROUP (ORDER BY total_ms)
+			FROM manager_checks
+			WHERE checked_at >= $1 AND checked_at < $2
+			GROUP BY 1, manager_url
+			ON CONFLICT (manager_url, bucket) DO UPDATE SET
+				checks = EXCLUDED.checks,
+				successes = EXCLUDED.successes,
+				degraded = EXCLUDED.degraded,
+				errors = EXCLUDED.errors,
+				success_ratio = EXCLUDED.success_ratio,
+				p50_ms = EXCLUDED.p50_ms,
+				p95_ms = EXCLUDED.p95_ms
+		`,
+	},
+	storage.RollupHealthCalls: {
+		timeColumn: "called_at",
+		aggregate: `
+			INSERT INTO %[1]s (bucket, calls)
+			SELECT date_trunc($3, called_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC', SUM(calls)
+			FROM health_calls
+			WHERE called_at >= $1 AND called_at < $2
+			GROUP BY 1
+			ON CONFLICT (bucket) DO UPDATE SET calls = EXCLUDED.calls
+		`,
+	},
+}
+
+// rollupTable returns the aggregate table of source at resolution, e.g. manager_checks_hourly.
+// It also names the watermark of the rollup.
+func rollupTable(source, resolution string) (string, error) {
+	if _, ok := rollupSources[source]; !ok {
+		return "", fmt.Errorf("unknown rollup source %q", source)
+	}
+	switch resolution {
+	case storage.RollupHourly:
+		return source + "_hourly", nil
+	case storage.RollupDaily:
+		return source + "_daily", nil
+	default:
+		return "", fmt.Errorf("unknown rollup resolution %q", resolution)
+	}
+}
+
+// RollupWatermark returns the end of the last rolled up bucket, zero if the rollup never ran
+func (s *Storage) RollupWatermark(ctx context.Context, source, resolution string) (time.Time, error) {
+	table, err := rollupTable(source, resolution)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	query := `
+		SELECT rolled_up_to
+		FROM rollup_watermarks
+		WHERE rollup = $1
+	`
+	var watermark time.Time
+	err = s.db.QueryRowContext(ctx, query, table).Scan(&watermark)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		s.logger.Error("failed to get rollup watermark", slog.String("rollup", table), slog.String("error", err.Error()))
+		return time.Time{}, fmt.Errorf("get rollup watermark: %w", err)
+	}
+	return watermark, nil
+}
+
+// Rollup aggregates raw rows within [from, to) and advances the watermark in one transaction,
+// so that raw rows are never purged before they are rolled up
+func (s *Storage) Rollup(ctx context.Context, source, resolution string, from, to time.Time) error {
+	table, err := rollupTable(source, resolution)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("rollup %s: begin: %w", table, err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(rollupSources[source].aggregate, table), from, to, resolution); err != nil {
+		s.logger.Error("failed to roll up", slog.String("rollup", table), slog.String("error", err.Error()))
+		return fmt.Errorf("rollup %s: %w", table, err)
+	}
+
+	query := `
+		INSERT INTO rollup_watermarks (rollup, rolled_up_to)
+		VALUES ($1, $2)
+		ON CONFLICT (rollup) DO UPDATE SET
+			rolled_up_to = GREATEST(rollup_watermarks.rolled_up_to, EXCLUDED.rolled_up_to)
+	`
+	if _, err := tx.ExecContext(ctx, query, table, to); err != nil {
+		return fmt.Errorf("rollup %s: advance watermark: %w", table, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("rollup %s: commit: %w", table, err)
+	}
+	return nil
+}
+
+// DeleteBefore deletes the oldest raw rows before the cutoff, at most limit in one statement.
+// Rows are matched together with their time so that partitioned tables are pruned.
+func (s *Storage) DeleteBefore(ctx context.Context, source string, before time.Time, limit int) (int64, error) {
+	src, ok := rollupSources[source]
+	if !ok {
+		return 0, fmt.Errorf("unknown rollup source %q", source)
+	}
+
+	query := fmt.Sprintf(`
+		DELETE FROM %[1]s
+		WHERE (id, %[2]s) IN (
+			SELECT id, %[2]s FROM %[1]s
+			WHERE %[2]s < $1
+			ORDER BY %[2]s
+			LIMIT $2
+		)
+	`, source, src.timeColumn)
+	result, err := s.db.ExecContext(ctx, query, before, limit)
+	if err != nil {
+		s.logger.Error("failed to delete expired rows", slog.String("table", source), slog.String("error", err.Error()))
+		return 0, fmt.Errorf("delete expired %s: %w", source, err)
+	}
+	return result.RowsAffected()
+}
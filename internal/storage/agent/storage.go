@@ -3,14 +3,17 @@ package agent
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/Shemistan/agent/internal/storage"
 )
 
-// Storage implements HealthStorage and ManagerCheckStorage interfaces
+// Storage implements HealthStorage, ManagerCheckStorage, ManagerCheckBatchStorage, ManagerCheckHistoryStorage,
+// ManagerSLAStorage, AlertStateStorage, SchemaStorage, RetentionStorage and PartitionStorage interfaces
 type Storage struct {
 	db     *sql.DB
 	logger *slog.Logger
@@ -25,12 +28,12 @@ func NewStorage(db *sql.DB, logger *slog.Logger) *Storage {
 }
 
 // SaveHealthCall saves a health check call to the database
-func (s *Storage) SaveHealthCall(ctx context.Context, calledAt time.Time) error {
+func (s *Storage) SaveHealthCall(ctx context.Context, call storage.HealthCall) error {
 	query := `
-		INSERT INTO health_calls (called_at)
-		VALUES ($1)
+		INSERT INTO health_calls (called_at, caller_ip, user_agent, latency_ms, calls)
+		VALUES ($1, $2, $3, $4, $5)
 	`
-	_, err := s.db.ExecContext(ctx, query, calledAt)
+	_, err := s.db.ExecContext(ctx, query, call.CalledAt, call.CallerIP, call.UserAgent, call.LatencyMillis, max(call.Calls, 1))
 	if err != nil {
 		s.logger.Error("failed to save health call", slog.String("error", err.Error()))
 		return fmt.Errorf("save health call: %w", err)
@@ -38,21 +41,262 @@ func (s *Storage) SaveHealthCall(ctx context.Context, calledAt time.Time) error
 	return nil
 }
 
-// SaveManagerCheck saves a manager health check to the database
+// SaveManagerCheck saves a manager health check to the database.
+// A check with a UID that is already stored is skipped.
 func (s *Storage) SaveManagerCheck(ctx context.Context, check storage.ManagerCheck) error {
+	return s.SaveManagerChecks(ctx, []storage.ManagerCheck{check})
+}
+
+// managerCheckColumns is the number of columns written per manager check
+const managerCheckColumns = 12
+
+// maxManagerChecksPerInsert keeps a multi-row INSERT within the 65535 bind parameters Postgres accepts
+const maxManagerChecksPerInsert = 65535 / managerCheckColumns
+
+// SaveManagerChecks saves manager checks with multi-row INSERTs in one transaction.
+// Checks with a UID that is already stored are skipped, so a batch can be retried safely.
+func (s *Storage) SaveManagerChecks(ctx context.Context, checks []storage.ManagerCheck) error {
+	if len(checks) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("save manager checks: begin: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for start := 0; start < len(checks); start += maxManagerChecksPerInsert {
+		chunk := checks[start:min(start+maxManagerChecksPerInsert, len(checks))]
+
+		values := make([]string, 0, len(chunk))
+		args := make([]any, 0, len(chunk)*managerCheckColumns)
+		for _, check := range chunk {
+			placeholders := make([]string, managerCheckColumns)
+			for i := range placeholders {
+				placeholders[i] = fmt.Sprintf("$%d", len(args)+i+1)
+			}
+			values = append(values, "("+strings.Join(placeholders, ", ")+")")
+			args = append(args,
+				check.CheckedAt, check.ManagerURL, check.Status, check.HTTPStatus, check.ErrorMessage,
+				check.DNSMillis, check.ConnectMillis, check.TLSMillis, check.TTFBMillis, check.TotalMillis, check.Attempts,
+				nullableString(check.UID),
+			)
+		}
+
+		query := `
+			INSERT INTO manager_checks (
+				checked_at, manager_url, status, http_status, error_message,
+				dns_ms, connect_ms, tls_ms, ttfb_ms, total_ms, attempts, check_uid
+			)
+			VALUES ` + strings.Join(values, ", ") + `
+			ON CONFLICT (check_uid, checked_at) DO NOTHING`
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			s.logger.Error("failed to save manager checks", slog.Int("checks", len(chunk)), slog.String("error", err.Error()))
+			return fmt.Errorf("save manager checks: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("save manager checks: commit: %w", err)
+	}
+	return nil
+}
+
+// ListManagerChecks returns stored manager checks matching the filter, newest first
+func (s *Storage) ListManagerChecks(ctx context.Context, filter storage.ManagerCheckFilter) ([]storage.ManagerCheck, error) {
+	var (
+		conds []string
+		args  []any
+	)
+	addCond := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if filter.ManagerURL != "" {
+		addCond("manager_url = $%d", filter.ManagerURL)
+	}
+	if filter.Status != "" {
+		addCond("status = $%d", filter.Status)
+	}
+	if !filter.From.IsZero() {
+		addCond("checked_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		addCond("checked_at < $%d", filter.To)
+	}
+	if !filter.BeforeCheckedAt.IsZero() || filter.BeforeID > 0 {
+		args = append(args, filter.BeforeCheckedAt, filter.BeforeID)
+		conds = append(conds, fmt.Sprintf("(checked_at, id) < ($%d, $%d)", len(args)-1, len(args)))
+	}
+
 	query := `
-		INSERT INTO manager_checks (checked_at, manager_url, status, http_status, error_message)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id
+		SELECT id, checked_at, manager_url, status, http_status, error_message,
+			dns_ms, connect_ms, tls_ms, ttfb_ms, total_ms, attempts
+		FROM manager_checks
 	`
-	var id int64
-	err := s.db.QueryRowContext(
-		ctx, query,
-		check.CheckedAt, check.ManagerURL, check.Status, check.HTTPStatus, check.ErrorMessage,
-	).Scan(&id)
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	args = append(args, filter.Limit)
+	// Rows replayed from an outbox get newer ids than checks made after them, so order by checked_at first
+	query += fmt.Sprintf(" ORDER BY checked_at DESC, id DESC LIMIT $%d", len(args))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		s.logger.Error("failed to save manager check", slog.String("error", err.Error()))
-		return fmt.Errorf("save manager check: %w", err)
+		s.logger.Error("failed to list manager checks", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("list manager checks: %w", err)
+	}
+	defer rows.Close()
+
+	checks := make([]storage.ManagerCheck, 0, filter.Limit)
+	for rows.Next() {
+		var check storage.ManagerCheck
+		if err := rows.Scan(
+			&check.ID, &check.CheckedAt, &check.ManagerURL, &check.Status, &check.HTTPStatus, &check.ErrorMessage,
+			&check.DNSMillis, &check.ConnectMillis, &check.TLSMillis, &check.TTFBMillis, &check.TotalMillis,
+			&check.Attempts,
+		); err != nil {
+			return nil, fmt.Errorf("scan manager check: %w", err)
+		}
+		checks = append(checks, check)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list manager checks: %w", err)
+	}
+	return checks, nil
+}
+
+// ListStatusRuns groups consecutive checks of a manager with the same status into runs
+func (s *Storage) ListStatusRuns(ctx context.Context, managerURL string, from, to time.Time) ([]storage.StatusRun, error) {
+	query := `
+		WITH ordered AS (
+			SELECT id, checked_at, status,
+				CASE WHEN status = LAG(status) OVER (ORDER BY checked_at, id) THEN 0 ELSE 1 END AS run_start
+			FROM manager_checks
+			WHERE manager_url = $1 AND checked_at >= $2 AND checked_at < $3
+		),
+		numbered AS (
+			SELECT checked_at, status,
+				SUM(run_start) OVER (ORDER BY checked_at, id ROWS UNBOUNDED PRECEDING) AS run_id
+			FROM ordered
+		)
+		SELECT status, MIN(checked_at), MAX(checked_at), COUNT(*)
+		FROM numbered
+		GROUP BY run_id, status
+		ORDER BY run_id
+	`
+	rows, err := s.db.QueryContext(ctx, query, managerURL, from, to)
+	if err != nil {
+		s.logger.Error("failed to list status runs", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("list status runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []storage.StatusRun
+	for rows.Next() {
+		var run storage.StatusRun
+		if err := rows.Scan(&run.Status, &run.StartedAt, &run.LastCheckedAt, &run.Checks); err != nil {
+			return nil, fmt.Errorf("scan status run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list status runs: %w", err)
+	}
+	return runs, nil
+}
+
+// OldestManagerCheck returns checked_at of the oldest stored raw check of a manager, zero if none
+func (s *Storage) OldestManagerCheck(ctx context.Context, managerURL string) (time.Time, error) {
+	query := `
+		SELECT MIN(checked_at)
+		FROM manager_checks
+		WHERE manager_url = $1
+	`
+	var oldest sql.NullTime
+	if err := s.db.QueryRowContext(ctx, query, managerURL).Scan(&oldest); err != nil {
+		s.logger.Error("failed to get oldest manager check", slog.String("error", err.Error()))
+		return time.Time{}, fmt.Errorf("get oldest manager check: %w", err)
+	}
+	return oldest.Time, nil
+}
+
+// GetAlertState returns the saved alerting state of a manager
+func (s *Storage) GetAlertState(ctx context.Context, managerURL string) (storage.AlertState, bool, error) {
+	query := `
+		SELECT manager_url, state, changed_at, last_notified_at, down_notified_at, recovered_notified_at
+		FROM manager_alert_states
+		WHERE manager_url = $1
+	`
+	var state storage.AlertState
+	err := s.db.QueryRowContext(ctx, query, managerURL).Scan(
+		&state.ManagerURL, &state.State, &state.ChangedAt,
+		&state.LastNotifiedAt, &state.DownNotifiedAt, &state.RecoveredNotifiedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.AlertState{}, false, nil
+	}
+	if err != nil {
+		s.logger.Error("failed to get alert state", slog.String("error", err.Error()))
+		return storage.AlertState{}, false, fmt.Errorf("get alert state: %w", err)
+	}
+	return state, true, nil
+}
+
+// SaveAlertState inserts or replaces the alerting state of a manager
+func (s *Storage) SaveAlertState(ctx context.Context, state storage.AlertState) error {
+	query := `
+		INSERT INTO manager_alert_states (
+			manager_url, state, changed_at, last_notified_at, down_notified_at, recovered_notified_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (manager_url) DO UPDATE SET
+			state = EXCLUDED.state,
+			changed_at = EXCLUDED.changed_at,
+			last_notified_at = EXCLUDED.last_notified_at,
+			down_notified_at = EXCLUDED.down_notified_at,
+			recovered_notified_at = EXCLUDED.recovered_notified_at
+	`
+	_, err := s.db.ExecContext(ctx, query,
+		state.ManagerURL, state.State, state.ChangedAt,
+		state.LastNotifiedAt, state.DownNotifiedAt, state.RecoveredNotifiedAt,
+	)
+	if err != nil {
+		s.logger.Error("failed to save alert state", slog.String("error", err.Error()))
+		return fmt.Errorf("save alert state: %w", err)
+	}
+	return nil
+}
+
+// Ping verifies that a database connection can be obtained and used
+func (s *Storage) Ping(ctx context.Context) error {
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("ping database: %w", err)
 	}
 	return nil
 }
+
+// SchemaVersion returns the latest migration version recorded by the migrator
+func (s *Storage) SchemaVersion(ctx context.Context) (int64, error) {
+	query := `
+		SELECT COALESCE(MAX(version), 0)
+		FROM schema_migrations
+	`
+	var version int64
+	if err := s.db.QueryRowContext(ctx, query).Scan(&version); err != nil {
+		return 0, fmt.Errorf("get schema version: %w", err)
+	}
+	return version, nil
+}
+
+// nullableString maps an empty string to NULL
+func nullableString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
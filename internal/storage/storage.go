@@ -5,22 +5,149 @@ import (
 	"time"
 )
 
+// HealthCall represents a recorded /health call
+type HealthCall struct {
+	CalledAt      time.Time
+	CallerIP      *string // nil when the remote address is not an IP
+	UserAgent     *string
+	LatencyMillis *float64
+	Calls         int // calls this row stands for when sampling, including itself
+}
+
 // HealthStorage defines the interface for health call storage operations
 type HealthStorage interface {
-	SaveHealthCall(ctx context.Context, calledAt time.Time) error
+	SaveHealthCall(ctx context.Context, call HealthCall) error
+}
+
+// SchemaStorage defines the interface for inspecting the database the agent depends on
+type SchemaStorage interface {
+	Ping(ctx context.Context) error
+	// SchemaVersion returns the latest applied migration version, 0 when none are applied
+	SchemaVersion(ctx context.Context) (int64, error)
 }
 
 // ManagerCheck represents a manager health check record
 type ManagerCheck struct {
 	ID           int64
+	UID          string // identifies the check across write retries so that it is stored once; empty means none
 	CheckedAt    time.Time
 	ManagerURL   string
-	Status       string // "success" или "error"
+	Status       string // "success", "degraded" или "error"
 	HTTPStatus   *int
 	ErrorMessage *string
+
+	// Timing breakdown in milliseconds; nil when the phase did not happen
+	DNSMillis     *float64
+	ConnectMillis *float64
+	TLSMillis     *float64
+	TTFBMillis    *float64
+	TotalMillis   *float64
+
+	Attempts int // requests made for this check, including retries
 }
 
 // ManagerCheckStorage defines the interface for manager check storage operations
 type ManagerCheckStorage interface {
 	SaveManagerCheck(ctx context.Context, check ManagerCheck) error
 }
+
+// ManagerCheckBatchStorage defines the interface for saving manager checks in bulk
+type ManagerCheckBatchStorage interface {
+	// SaveManagerChecks saves all checks in as few round-trips as possible; either all or none are saved
+	SaveManagerChecks(ctx context.Context, checks []ManagerCheck) error
+}
+
+// ManagerCheckFilter describes which stored manager checks to list
+type ManagerCheckFilter struct {
+	ManagerURL string    // exact match, empty means any manager
+	Status     string    // exact match, empty means any status
+	From       time.Time // inclusive lower bound of checked_at, zero means unbounded
+	To         time.Time // exclusive upper bound of checked_at, zero means unbounded
+
+	// Cursor: only checks before (BeforeCheckedAt, BeforeID) in checked_at and then id order;
+	// the zero position starts from the newest
+	BeforeCheckedAt time.Time
+	BeforeID        int64
+	Limit           int
+}
+
+// ManagerCheckHistoryStorage defines the interface for reading stored manager checks
+type ManagerCheckHistoryStorage interface {
+	// ListManagerChecks returns matching checks ordered by checked_at and then id, newest first
+	ListManagerChecks(ctx context.Context, filter ManagerCheckFilter) ([]ManagerCheck, error)
+}
+
+// StatusRun represents a maximal sequence of consecutive checks of one manager with the same status
+type StatusRun struct {
+	Status        string
+	StartedAt     time.Time // checked_at of the first check in the run
+	LastCheckedAt time.Time // checked_at of the last check in the run
+	Checks        int64
+}
+
+// ManagerSLAStorage defines the interface for availability aggregation queries
+type ManagerSLAStorage interface {
+	// ListStatusRuns returns status runs of a manager within [from, to), oldest first
+	ListStatusRuns(ctx context.Context, managerURL string, from, to time.Time) ([]StatusRun, error)
+	// OldestManagerCheck returns checked_at of the oldest stored raw check of a manager, zero if none
+	OldestManagerCheck(ctx context.Context, managerURL string) (time.Time, error)
+}
+
+// AlertState represents the last known alerting state of a manager
+type AlertState struct {
+	ManagerURL          string
+	State               string // "up" or "down"
+	ChangedAt           time.Time
+	LastNotifiedAt      *time.Time // last notification of any kind
+	DownNotifiedAt      *time.Time // last down notification, used for dedup
+	RecoveredNotifiedAt *time.Time // last recovered notification, used for dedup
+}
+
+// AlertStateStorage defines the interface for persisting manager alerting state
+type AlertStateStorage interface {
+	// GetAlertState returns the state of a manager, found is false if none was saved yet
+	GetAlertState(ctx context.Context, managerURL string) (state AlertState, found bool, err error)
+	SaveAlertState(ctx context.Context, state AlertState) error
+}
+
+// Rollup resolutions; each names a date_trunc field and selects the *_hourly or *_daily tables
+const (
+	RollupHourly = "hour"
+	RollupDaily  = "day"
+)
+
+// Rollup sources, the raw tables that are aggregated and purged
+const (
+	RollupManagerChecks = "manager_checks"
+	RollupHealthCalls   = "health_calls"
+)
+
+// RetentionStorage defines the interface for downsampling and purging raw history
+type RetentionStorage interface {
+	// RollupWatermark returns the end of the last rolled up bucket of source at resolution, zero if none
+	RollupWatermark(ctx context.Context, source, resolution string) (time.Time, error)
+	// Rollup aggregates raw rows of source within [from, to) into buckets of resolution
+	// and advances the watermark to to; from and to must be bucket boundaries
+	Rollup(ctx context.Context, source, resolution string, from, to time.Time) error
+	// DeleteBefore deletes at most limit raw rows of source older than before
+	// and returns how many were deleted
+	DeleteBefore(ctx context.Context, source string, before time.Time, limit int) (int64, error)
+}
+
+// Partition represents a range partition of manager_checks
+type Partition struct {
+	Name string
+	From time.Time // zero for the legacy partition holding all rows before the monthly ones
+	To   time.Time // exclusive
+}
+
+// PartitionStorage defines the interface for maintaining monthly partitions of manager_checks
+type PartitionStorage interface {
+	// ListPartitions returns the range partitions ordered by From, empty when the table is not partitioned
+	ListPartitions(ctx context.Context) ([]Partition, error)
+	// CreatePartition creates the partition of the UTC month starting at from,
+	// moving rows of that month out of the default partition
+	CreatePartition(ctx context.Context, from time.Time) error
+	// RemovePartition detaches the named partition and drops it unless detachOnly is set
+	RemovePartition(ctx context.Context, name string, detachOnly bool) error
+}
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_manager_checks_manager_url_id;
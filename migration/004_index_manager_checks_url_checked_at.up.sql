@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_manager_checks_manager_url_checked_at ON manager_checks(manager_url, checked_at);
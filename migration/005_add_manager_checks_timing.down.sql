@@ -0,0 +1,6 @@
+ALTER TABLE manager_checks
+    DROP COLUMN IF EXISTS dns_ms,
+    DROP COLUMN IF EXISTS connect_ms,
+    DROP COLUMN IF EXISTS tls_ms,
+    DROP COLUMN IF EXISTS ttfb_ms,
+    DROP COLUMN IF EXISTS total_ms;
@@ -0,0 +1,6 @@
+ALTER TABLE manager_checks
+    ADD COLUMN IF NOT EXISTS dns_ms DOUBLE PRECISION NULL,
+    ADD COLUMN IF NOT EXISTS connect_ms DOUBLE PRECISION NULL,
+    ADD COLUMN IF NOT EXISTS tls_ms DOUBLE PRECISION NULL,
+    ADD COLUMN IF NOT EXISTS ttfb_ms DOUBLE PRECISION NULL,
+    ADD COLUMN IF NOT EXISTS total_ms DOUBLE PRECISION NULL;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS manager_alert_states;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS manager_alert_states (
+    manager_url TEXT PRIMARY KEY,
+    state TEXT NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL,
+    last_notified_at TIMESTAMPTZ NULL,
+    down_notified_at TIMESTAMPTZ NULL,
+    recovered_notified_at TIMESTAMPTZ NULL
+);
@@ -0,0 +1,2 @@
+ALTER TABLE manager_checks
+    DROP COLUMN IF EXISTS attempts;
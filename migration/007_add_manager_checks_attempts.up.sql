@@ -0,0 +1,2 @@
+ALTER TABLE manager_checks
+    ADD COLUMN IF NOT EXISTS attempts INT NOT NULL DEFAULT 1;
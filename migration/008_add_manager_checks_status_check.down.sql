@@ -0,0 +1,2 @@
+ALTER TABLE manager_checks
+    DROP CONSTRAINT IF EXISTS manager_checks_status_check;
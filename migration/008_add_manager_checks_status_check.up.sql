@@ -0,0 +1,4 @@
+ALTER TABLE manager_checks
+    DROP CONSTRAINT IF EXISTS manager_checks_status_check;
+ALTER TABLE manager_checks
+    ADD CONSTRAINT manager_checks_status_check CHECK (status IN ('success', 'degraded', 'error'));
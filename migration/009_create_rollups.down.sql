@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS rollup_watermarks;
+DROP TABLE IF EXISTS health_calls_daily;
+DROP TABLE IF EXISTS health_calls_hourly;
+DROP TABLE IF EXISTS manager_checks_daily;
+DROP TABLE IF EXISTS manager_checks_hourly;
@@ -0,0 +1,41 @@
+CREATE TABLE IF NOT EXISTS manager_checks_hourly (
+    bucket TIMESTAMPTZ NOT NULL,
+    manager_url TEXT NOT NULL,
+    checks BIGINT NOT NULL,
+    successes BIGINT NOT NULL,
+    degraded BIGINT NOT NULL,
+    errors BIGINT NOT NULL,
+    success_ratio DOUBLE PRECISION NOT NULL,
+    p50_ms DOUBLE PRECISION NULL,
+    p95_ms DOUBLE PRECISION NULL,
+    PRIMARY KEY (manager_url, bucket)
+);
+
+CREATE TABLE IF NOT EXISTS manager_checks_daily (
+    bucket TIMESTAMPTZ NOT NULL,
+    manager_url TEXT NOT NULL,
+    checks BIGINT NOT NULL,
+    successes BIGINT NOT NULL,
+    degraded BIGINT NOT NULL,
+    errors BIGINT NOT NULL,
+    success_ratio DOUBLE PRECISION NOT NULL,
+    p50_ms DOUBLE PRECISION NULL,
+    p95_ms DOUBLE PRECISION NULL,
+    PRIMARY KEY (manager_url, bucket)
+);
+
+CREATE TABLE IF NOT EXISTS health_calls_hourly (
+    bucket TIMESTAMPTZ PRIMARY KEY,
+    calls BIGINT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS health_calls_daily (
+    bucket TIMESTAMPTZ PRIMARY KEY,
+    calls BIGINT NOT NULL
+);
+
+-- Raw rows before rolled_up_to have been aggregated into the rollup and may be purged
+CREATE TABLE IF NOT EXISTS rollup_watermarks (
+    rollup TEXT PRIMARY KEY,
+    rolled_up_to TIMESTAMPTZ NOT NULL
+);
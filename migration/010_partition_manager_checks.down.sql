@@ -0,0 +1,20 @@
+-- Copy all partitions back into a single flat table
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_partitioned_table WHERE partrelid = 'manager_checks'::regclass) THEN
+        RETURN;
+    END IF;
+
+    CREATE TABLE manager_checks_flat (LIKE manager_checks INCLUDING DEFAULTS INCLUDING CONSTRAINTS);
+    INSERT INTO manager_checks_flat SELECT * FROM manager_checks;
+    ALTER SEQUENCE manager_checks_id_seq OWNED BY manager_checks_flat.id;
+
+    DROP TABLE manager_checks;
+    ALTER TABLE manager_checks_flat RENAME TO manager_checks;
+    ALTER TABLE manager_checks ADD CONSTRAINT manager_checks_pkey PRIMARY KEY (id);
+
+    CREATE INDEX idx_manager_checks_checked_at ON manager_checks(checked_at);
+    CREATE INDEX idx_manager_checks_status ON manager_checks(status);
+    CREATE INDEX idx_manager_checks_manager_url_id ON manager_checks(manager_url, id DESC);
+    CREATE INDEX idx_manager_checks_manager_url_checked_at ON manager_checks(manager_url, checked_at);
+END $$;
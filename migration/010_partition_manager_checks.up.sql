@@ -0,0 +1,138 @@
+-- migrate:no-transaction
+-- Turn manager_checks into a table range-partitioned by month without copying rows:
+-- the existing table is attached as the manager_checks_legacy partition, and monthly partitions
+-- manager_checks_pYYYYMM follow. The agent creates further partitions ahead of time and removes
+-- expired ones.
+--
+-- Everything that reads the whole table runs without blocking writes, so that the final swap
+-- only changes metadata. Each step is skipped once done, a failed run can be repeated.
+
+-- 1. Bound the existing rows. NOT VALID skips the scan but already applies to new rows,
+-- so the bound leaves room for the current and the next month.
+DO $$
+DECLARE
+    legacy_end TIMESTAMPTZ;
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_partitioned_table WHERE partrelid = 'manager_checks'::regclass)
+        OR EXISTS (
+            SELECT 1 FROM pg_constraint
+            WHERE conrelid = 'manager_checks'::regclass AND conname = 'manager_checks_legacy_range'
+        ) THEN
+        RETURN;
+    END IF;
+
+    SELECT date_trunc('month', GREATEST(MAX(checked_at), NOW()) AT TIME ZONE 'UTC') AT TIME ZONE 'UTC'
+        + INTERVAL '2 months'
+    INTO legacy_end
+    FROM manager_checks;
+
+    SET LOCAL lock_timeout = '10s';
+    EXECUTE format(
+        'ALTER TABLE manager_checks ADD CONSTRAINT manager_checks_legacy_range CHECK (checked_at < %L) NOT VALID',
+        legacy_end
+    );
+END $$;
+
+-- 2. Validate the bound; this scans the table but lets writes through
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conrelid = 'manager_checks'::regclass AND conname = 'manager_checks_legacy_range' AND NOT convalidated
+    ) THEN
+        ALTER TABLE manager_checks VALIDATE CONSTRAINT manager_checks_legacy_range;
+    END IF;
+END $$;
+
+-- 3. Build the unique (id, checked_at) index required by the partitioned primary key online.
+-- An invalid index left behind by an interrupted build is dropped first.
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM pg_index
+        WHERE indexrelid = to_regclass('manager_checks_id_checked_at_key') AND NOT indisvalid
+    ) THEN
+        SET LOCAL lock_timeout = '10s';
+        DROP INDEX manager_checks_id_checked_at_key;
+    END IF;
+END $$;
+
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS manager_checks_id_checked_at_key ON manager_checks(id, checked_at);
+
+-- 4. Swap in the partitioned table. The validated bound proves that the legacy rows fit their
+-- partition and the existing indexes are attached to the parent ones, so nothing is scanned or rebuilt.
+DO $$
+DECLARE
+    legacy_end TIMESTAMPTZ;
+    part_start TIMESTAMPTZ;
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_partitioned_table WHERE partrelid = 'manager_checks'::regclass) THEN
+        RETURN;
+    END IF;
+
+    SELECT substring(pg_get_constraintdef(oid) FROM '''([^'']+)''')::timestamptz
+    INTO legacy_end
+    FROM pg_constraint
+    WHERE conrelid = 'manager_checks'::regclass AND conname = 'manager_checks_legacy_range' AND convalidated;
+
+    IF legacy_end IS NULL THEN
+        RAISE EXCEPTION 'manager_checks_legacy_range is missing or not validated';
+    END IF;
+
+    SET LOCAL lock_timeout = '10s';
+
+    ALTER TABLE manager_checks RENAME TO manager_checks_legacy;
+    ALTER TABLE manager_checks_legacy DROP CONSTRAINT manager_checks_pkey;
+    ALTER TABLE manager_checks_legacy
+        ADD CONSTRAINT manager_checks_legacy_pkey PRIMARY KEY USING INDEX manager_checks_id_checked_at_key;
+    ALTER INDEX idx_manager_checks_checked_at RENAME TO idx_manager_checks_legacy_checked_at;
+    ALTER INDEX idx_manager_checks_status RENAME TO idx_manager_checks_legacy_status;
+    ALTER INDEX idx_manager_checks_manager_url_id RENAME TO idx_manager_checks_legacy_manager_url_id;
+    ALTER INDEX idx_manager_checks_manager_url_checked_at RENAME TO idx_manager_checks_legacy_manager_url_checked_at;
+
+    CREATE TABLE manager_checks (
+        id INTEGER NOT NULL DEFAULT nextval('manager_checks_id_seq'),
+        checked_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        manager_url TEXT NOT NULL,
+        status TEXT NOT NULL,
+        http_status INT NULL,
+        error_message TEXT NULL,
+        dns_ms DOUBLE PRECISION NULL,
+        connect_ms DOUBLE PRECISION NULL,
+        tls_ms DOUBLE PRECISION NULL,
+        ttfb_ms DOUBLE PRECISION NULL,
+        total_ms DOUBLE PRECISION NULL,
+        attempts INT NOT NULL DEFAULT 1,
+        CONSTRAINT manager_checks_status_check CHECK (status IN ('success', 'degraded', 'error')),
+        PRIMARY KEY (id, checked_at)
+    ) PARTITION BY RANGE (checked_at);
+
+    -- The sequence would otherwise be dropped together with the legacy partition
+    ALTER SEQUENCE manager_checks_id_seq OWNED BY manager_checks.id;
+
+    -- Equivalent indexes of the legacy table are attached instead of being rebuilt
+    CREATE INDEX idx_manager_checks_checked_at ON manager_checks(checked_at);
+    CREATE INDEX idx_manager_checks_status ON manager_checks(status);
+    CREATE INDEX idx_manager_checks_manager_url_id ON manager_checks(manager_url, id DESC);
+    CREATE INDEX idx_manager_checks_manager_url_checked_at ON manager_checks(manager_url, checked_at);
+
+    EXECUTE format(
+        'ALTER TABLE manager_checks ATTACH PARTITION manager_checks_legacy FOR VALUES FROM (MINVALUE) TO (%L)',
+        legacy_end
+    );
+    -- Redundant with the partition bound from now on
+    ALTER TABLE manager_checks_legacy DROP CONSTRAINT manager_checks_legacy_range;
+
+    FOR i IN 0..1 LOOP
+        part_start := legacy_end + make_interval(months => i);
+        EXECUTE format(
+            'CREATE TABLE IF NOT EXISTS %I PARTITION OF manager_checks FOR VALUES FROM (%L) TO (%L)',
+            'manager_checks_p' || to_char(part_start AT TIME ZONE 'UTC', 'YYYYMM'),
+            part_start,
+            part_start + INTERVAL '1 month'
+        );
+    END LOOP;
+
+    -- Catches rows outside of the created partitions, e.g. when the agent could not create them in time
+    CREATE TABLE IF NOT EXISTS manager_checks_default PARTITION OF manager_checks DEFAULT;
+END $$;
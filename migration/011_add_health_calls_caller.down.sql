@@ -0,0 +1,5 @@
+ALTER TABLE health_calls
+    DROP COLUMN IF EXISTS calls,
+    DROP COLUMN IF EXISTS latency_ms,
+    DROP COLUMN IF EXISTS user_agent,
+    DROP COLUMN IF EXISTS caller_ip;
@@ -0,0 +1,6 @@
+-- Caller details of sampled /health calls; calls is the number of calls the row stands for
+ALTER TABLE health_calls
+    ADD COLUMN IF NOT EXISTS caller_ip INET NULL,
+    ADD COLUMN IF NOT EXISTS user_agent TEXT NULL,
+    ADD COLUMN IF NOT EXISTS latency_ms DOUBLE PRECISION NULL,
+    ADD COLUMN IF NOT EXISTS calls INT NOT NULL DEFAULT 1;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_manager_checks_check_uid;
+
+ALTER TABLE manager_checks
+    DROP COLUMN IF EXISTS check_uid;
@@ -0,0 +1,53 @@
+-- migrate:no-transaction
+-- Identifies a check so that replays from the agent's local outbox are idempotent.
+-- Unique indexes of a partitioned table must include the partition key.
+--
+-- The index is built without blocking writes: the parent index is created ON ONLY the parent
+-- and stays invalid until every partition has an index attached to it; the partition indexes
+-- are built concurrently. Partitions created meanwhile get their index when they are attached.
+-- Statements of this migration have no deadline, so that index builds on large partitions can complete;
+-- the lock of the column change is bounded instead.
+DO $$
+BEGIN
+    SET LOCAL lock_timeout = '10s';
+    ALTER TABLE manager_checks
+        ADD COLUMN IF NOT EXISTS check_uid UUID NULL;
+END $$;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_manager_checks_check_uid ON ONLY manager_checks(check_uid, checked_at);
+
+-- Partition indexes left invalid by an interrupted build
+-- migrate:gexec
+SELECT format('DROP INDEX CONCURRENTLY IF EXISTS %I', i.relname)
+FROM pg_inherits p
+JOIN pg_class t ON t.oid = p.inhrelid
+JOIN pg_index x ON x.indrelid = t.oid
+JOIN pg_class i ON i.oid = x.indexrelid
+WHERE p.inhparent = 'manager_checks'::regclass
+    AND i.relname = t.relname || '_check_uid_idx'
+    AND NOT x.indisvalid;
+
+-- migrate:gexec
+SELECT format('CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS %I ON %I(check_uid, checked_at)', t.relname || '_check_uid_idx', t.relname)
+FROM pg_inherits p
+JOIN pg_class t ON t.oid = p.inhrelid
+WHERE p.inhparent = 'manager_checks'::regclass
+    AND NOT EXISTS (
+        SELECT 1 FROM pg_inherits ip
+        JOIN pg_index x ON x.indexrelid = ip.inhrelid
+        WHERE ip.inhparent = 'idx_manager_checks_check_uid'::regclass AND x.indrelid = t.oid
+    )
+ORDER BY t.relname;
+
+-- Attaching the last partition index marks the parent index valid
+-- migrate:gexec
+SELECT format('ALTER INDEX idx_manager_checks_check_uid ATTACH PARTITION %I', t.relname || '_check_uid_idx')
+FROM pg_inherits p
+JOIN pg_class t ON t.oid = p.inhrelid
+WHERE p.inhparent = 'manager_checks'::regclass
+    AND NOT EXISTS (
+        SELECT 1 FROM pg_inherits ip
+        JOIN pg_index x ON x.indexrelid = ip.inhrelid
+        WHERE ip.inhparent = 'idx_manager_checks_check_uid'::regclass AND x.indrelid = t.oid
+    )
+ORDER BY t.relname;
@@ -0,0 +1,9 @@
+// Package migration embeds the SQL migrations so that binaries know the schema version they were built for
+package migration
+
+import "embed"
+
+// FS contains the up and down migration files
+//
+//go:embed *.sql
+var FS embed.FS